@@ -0,0 +1,207 @@
+package main
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/account"
+	"adv-mod/internal/admin"
+	"adv-mod/internal/apikey"
+	"adv-mod/internal/audit"
+	"adv-mod/internal/auth"
+	"adv-mod/internal/health"
+	"adv-mod/internal/link"
+	"adv-mod/internal/session"
+	"adv-mod/internal/stat"
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+	"adv-mod/pkg/db"
+	"adv-mod/pkg/event"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/mailer"
+	"adv-mod/pkg/metrics"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/ratelimit"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/response"
+	"context"
+	"io"
+	"net/http"
+)
+
+// eventBusSize - сколько событий может ждать обработки у одного подписчика,
+// прежде чем шина начнёт их отбрасывать
+const eventBusSize = 1024
+
+// Application - роутер, шина событий и фоновые задачи. Задачи работают до отмены ctx,
+// Closers закрываются после них
+type Application struct {
+	Handler  http.Handler
+	EventBus *event.Bus
+	Workers  []func(ctx context.Context)
+	Closers  []io.Closer
+}
+
+// App собирает зависимости и роутер приложения
+func App(conf *configs.Config, database *db.Db) (*Application, error) {
+	router := http.NewServeMux()
+
+	// Repositories
+	userRepository := user.NewUserRepository(database)
+	refreshTokenRepository := session.NewRefreshTokenRepository(database)
+	verificationTokenRepository := verification.NewTokenRepository(database)
+	linkRepository := link.NewLinkRepository(database)
+	statRepository := stat.NewStatRepository(database)
+	auditRepository := audit.NewAuditRepository(database)
+	apiKeyRepository := apikey.NewApiKeyRepository(database)
+	err := userRepository.PromoteAdmins(conf.Auth.AdminEmails)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stores
+	eventBus := event.NewBus(eventBusSize)
+	rateLimitStore := ratelimit.NewMemoryStore()
+	var mail mailer.Mailer = mailer.NewFileMailer(conf.Mail.OutboxDir, conf.Mail.From)
+	if conf.Mail.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(conf.Mail)
+	}
+
+	// Metrics
+	registry := metrics.NewRegistry()
+	database.RegisterMetrics(registry)
+
+	// Audit
+	var closers []io.Closer
+	var auditSink audit.Sink = auditRepository
+	if conf.Audit.Sink == configs.AuditSinkFile {
+		fileSink, err := audit.NewFileSink(conf.Audit.File)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, fileSink)
+		auditSink = fileSink
+	}
+	auditRecorder := audit.NewRecorder(auditSink, registry)
+
+	// Services
+	jwtService := jwt.NewJWT(conf.Auth.Secret)
+	jwtService.TTL = conf.Auth.AccessTTL
+	authService := auth.NewAuthService(auth.AuthServiceDeps{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		JWT:                    jwtService,
+		RefreshTTL:             conf.Auth.RefreshTTL,
+		Lockout:                rateLimitStore,
+		LockoutPolicy: ratelimit.LockoutPolicy{
+			MaxFailures:  conf.RateLimit.MaxFailedLogins,
+			BaseDuration: conf.RateLimit.LockoutBase,
+			MaxDuration:  conf.RateLimit.LockoutMax,
+		},
+		VerificationTokenRepository: verificationTokenRepository,
+		Mailer:                      mail,
+		VerifyEmailTTL:              conf.Auth.VerifyEmailTTL,
+		ResetPasswordTTL:            conf.Auth.ResetPasswordTTL,
+		BaseURL:                     conf.Mail.BaseURL,
+		Metrics:                     auth.NewAuthMetrics(registry),
+	})
+	accountService := account.NewAccountService(account.AccountServiceDeps{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		Verifier:               authService,
+	})
+	apiKeyService := apikey.NewApiKeyService(apikey.ApiKeyServiceDeps{
+		ApiKeyRepository: apiKeyRepository,
+		UserRepository:   userRepository,
+	})
+
+	// Handlers
+	api := openapi.NewRegistry("adv-mod", "1.0.0")
+	auth.NewHelloHandler(router, auth.AuthHandlerDeps{
+		Config:         conf,
+		AuthService:    authService,
+		RateLimitStore: rateLimitStore,
+		Audit:          auditRecorder,
+		OpenAPI:        api,
+	})
+	account.NewHelloHandler(router, account.AccountHandlerDeps{
+		Config:         conf,
+		AccountService: accountService,
+		Audit:          auditRecorder,
+		OpenAPI:        api,
+	})
+	apikey.NewHelloHandler(router, apikey.ApiKeyHandlerDeps{
+		Config:        conf,
+		ApiKeyService: apiKeyService,
+		Audit:         auditRecorder,
+		OpenAPI:       api,
+	})
+	admin.NewHelloHandler(router, admin.AdminHandlerDeps{
+		Config:                 conf,
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		Audit:                  auditRecorder,
+		OpenAPI:                api,
+	})
+	// Журнал в файле через API не читается
+	if conf.Audit.Sink == configs.AuditSinkDb {
+		audit.NewHelloHandler(router, audit.AuditHandlerDeps{
+			Config:          conf,
+			AuditRepository: auditRepository,
+			OpenAPI:         api,
+		})
+	}
+	link.NewHelloHandler(router, link.LinkHandlerDeps{
+		Config:         conf,
+		LinkRepository: linkRepository,
+		EventBus:       eventBus,
+		OpenAPI:        api,
+	})
+	stat.NewHelloHandler(router, stat.StatHandlerDeps{
+		Config:         conf,
+		StatRepository: statRepository,
+		OpenAPI:        api,
+	})
+	health.NewHelloHandler(router, health.HealthHandlerDeps{
+		Checks: map[string]health.Pinger{
+			"database": database,
+		},
+		OpenAPI: api,
+	})
+	api.Routes(router, conf.Server.SwaggerUI)
+	canReadMetrics := middleware.RequirePermission(rbac.PermissionMetricsRead)
+	api.Handle(router, "GET /metrics", middleware.IsAuthed(canReadMetrics(registry.Handler()), conf), openapi.Operation{
+		Summary:     "Application metrics",
+		Description: "Prometheus text format. Scrape with an api key that has the " + rbac.PermissionMetricsRead + " scope.",
+		Tags:        []string{"admin"},
+		Responses:   map[int]any{200: nil, 401: response.ErrorBody{}, 403: response.ErrorBody{}},
+		Security:    []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	// router.HandleFunc("/hello", hello)
+
+	// Middlewares
+	stack := middleware.Chain(
+		middleware.RequestID,
+		response.Negotiate,
+		middleware.Logging,
+		middleware.Recovery,
+		middleware.CORS(middleware.DefaultCORSOptions()),
+		// Ключ проверяет IsAuthed, здесь только передаётся lookup
+		middleware.APIKey(apiKeyService.Authenticate),
+		// Последним, чтобы видеть r.Pattern, который выставляет роутер
+		middleware.Metrics(registry),
+	)
+
+	// Workers
+	statWorker := stat.NewStatWorker(stat.StatWorkerDeps{
+		StatRepository: statRepository,
+		EventBus:       eventBus,
+		Registry:       registry,
+	})
+
+	return &Application{
+		Handler:  stack(router),
+		EventBus: eventBus,
+		Workers:  []func(ctx context.Context){statWorker.Run},
+		Closers:  closers,
+	}, nil
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"adv-mod/configs"
-	"adv-mod/internal/auth"
+	"adv-mod/migrations"
 	"adv-mod/pkg/db"
-	"fmt"
-	"net/http"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 // func hello(w http.ResponseWriter, req *http.Request) {
@@ -14,20 +18,31 @@ import (
 // }
 
 func main() {
-	conf := configs.LoadConfig()
-	_ = db.NewDb(conf)
-	router := http.NewServeMux()
-	auth.NewHelloHandler(router, auth.AuthHandlerDeps{
-		Config: conf,
-	})
-	// router.HandleFunc("/hello", hello)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	server := http.Server{
-		Addr:    ":8081",
-		Handler: router,
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := migrate(ctx, os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	fmt.Println("Server is listening on port 8081")
-	server.ListenAndServe()
+	conf := configs.LoadConfig()
+	slog.Info("config loaded", "config", conf.Redacted())
+	database, err := db.NewDb(ctx, conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = migrateOnStart(ctx, conf.Db, database, migrations.FS)
+	if err != nil {
+		database.Close()
+		log.Fatal(err)
+	}
 
+	err = run(ctx, conf, database, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
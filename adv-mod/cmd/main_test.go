@@ -0,0 +1,413 @@
+package main
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/apikey"
+	"adv-mod/internal/audit"
+	"adv-mod/internal/dbtest"
+	"adv-mod/internal/link"
+	"adv-mod/internal/session"
+	"adv-mod/internal/stat"
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+	"adv-mod/pkg/db"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/rbac"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDb(t *testing.T) *db.Db {
+	t.Helper()
+	return dbtest.Open(t, &user.User{}, &session.RefreshToken{}, &verification.Token{}, &link.Link{}, &stat.Stat{}, &audit.Entry{}, &apikey.ApiKey{})
+}
+
+func newTestConfig(t *testing.T) *configs.Config {
+	conf := configs.Default()
+	conf.Mail.OutboxDir = t.TempDir()
+	conf.Db.Dsn = "sqlite"
+	conf.Auth.Secret = "secret"
+	conf.Server.ShutdownTimeout = 5 * time.Second
+	return conf
+}
+
+func TestRunLifecycle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := newTestDb(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, newTestConfig(t), database, listener)
+	}()
+
+	url := "http://" + listener.Addr().String() + "/auth/register"
+	body := []byte(`{"email":"a@a.ru","password":"Secret123","name":"Vasya"}`)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if resp.Header.Get("X-Request-ID") == "" {
+		t.Error("Middlewares are not applied")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected shutdown error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not stop")
+	}
+	sqlDB, _ := database.DB.DB()
+	if sqlDB.Ping() == nil {
+		t.Error("Database pool is not closed")
+	}
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	conf := newTestConfig(t).Server
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, newServer(conf, handler), listener, conf)
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+	cancel()
+
+	if code := <-status; code != http.StatusOK {
+		t.Errorf("In-flight request was not drained, got status %d", code)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestServeShutdownDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	conf := newTestConfig(t).Server
+	conf.ShutdownTimeout = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, newServer(conf, handler), listener, conf)
+	}()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected deadline error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown ignored its deadline")
+	}
+}
+
+func TestAppServesOpenAPI(t *testing.T) {
+	app, err := App(newTestConfig(t), newTestDb(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := app.Handler
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d", http.StatusOK, w.Code)
+	}
+	var doc openapi.Document
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/auth/login", "/admin/users", "/readyz", "/link", "/stat", "/api-keys", "/metrics"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("Expected %s in document", path)
+		}
+	}
+}
+
+func TestAppServesMetrics(t *testing.T) {
+	conf := newTestConfig(t)
+	app, err := App(conf, newTestDb(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := app.Handler
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	testCases := []struct {
+		name   string
+		role   string
+		status int
+	}{
+		{name: "Anonymous", status: http.StatusUnauthorized},
+		{name: "User", role: rbac.RoleUser, status: http.StatusForbidden},
+		{name: "Admin", role: rbac.RoleAdmin, status: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.role != "" {
+				token, _ := jwt.NewJWT(conf.Auth.Secret).Create(jwt.JWTData{Email: "a@a.ru", Role: tc.role})
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+
+	token, _ := jwt.NewJWT(conf.Auth.Secret).Create(jwt.JWTData{Email: "a@a.ru", Role: rbac.RoleAdmin})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	for _, line := range []string{
+		`http_requests_total{route="/healthz",method="GET",status="200"} 1`,
+		`db_open_connections `,
+		`# TYPE auth_logins_total counter`,
+	} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("Expected %q in:\n%s", line, w.Body.String())
+		}
+	}
+}
+
+func TestAppRegisterAfterDelete(t *testing.T) {
+	app, err := App(newTestConfig(t), newTestDb(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	register := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{"email":"a@a.ru","password":"Secret123","name":"Vasya"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		app.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := register()
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(w.Body).Decode(&tokens)
+	if w := register(); w.Code != http.StatusConflict {
+		t.Fatalf("Expected %d for taken email, got %d", http.StatusConflict, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.Token)
+	w = httptest.NewRecorder()
+	app.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+
+	if w := register(); w.Code != http.StatusCreated {
+		t.Errorf("Expected email of deleted user to be free, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAppRecordsAudit(t *testing.T) {
+	testCases := []struct {
+		name string
+		sink string
+	}{
+		{name: "Database", sink: configs.AuditSinkDb},
+		{name: "File", sink: configs.AuditSinkFile},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := newTestConfig(t)
+			conf.Audit.Sink = tc.sink
+			conf.Audit.File = filepath.Join(t.TempDir(), "audit.log")
+			database := newTestDb(t)
+			app, err := App(conf, database)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"a@a.ru","password":"Secret123"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			app.Handler.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+			// Запись синхронная: журнал заполнен до ответа, без остановки шины
+			for _, closer := range app.Closers {
+				closer.Close()
+			}
+
+			var entries []audit.Entry
+			if tc.sink == configs.AuditSinkDb {
+				database.Find(&entries)
+			} else {
+				data, err := os.ReadFile(conf.Audit.File)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var entry audit.Entry
+				if err := json.Unmarshal(data, &entry); err != nil {
+					t.Fatal(err)
+				}
+				entries = append(entries, entry)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("Expected one audit entry, got %d", len(entries))
+			}
+			if entries[0].Action != audit.ActionLoginFailure || entries[0].ActorEmail != "a@a.ru" || entries[0].RequestId != "req-1" {
+				t.Errorf("Unexpected audit entry %+v", entries[0])
+			}
+		})
+	}
+}
+
+func TestAppApiKeys(t *testing.T) {
+	database := newTestDb(t)
+	app, err := App(newTestConfig(t), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		app.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/auth/register", `{"email":"a@a.ru","password":"Secret123","name":"Vasya"}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(w.Body).Decode(&tokens)
+	bearer := http.Header{"Authorization": {"Bearer " + tokens.Token}}
+
+	w = send(http.MethodPost, "/api-keys", `{"name":"ci","scopes":["users:read"]}`, bearer)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected scope above role to be rejected with %d, got %d", http.StatusForbidden, w.Code)
+	}
+	w = send(http.MethodPost, "/api-keys", `{"name":"ci","scopes":["links:read","links:write"]}`, bearer)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created apikey.CreateApiKeyResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if !strings.HasPrefix(created.Key, created.Prefix) || len(created.Prefix) != apikey.PrefixLength {
+		t.Fatalf("Expected key %q to start with prefix %q", created.Key, created.Prefix)
+	}
+	key := http.Header{"X-Api-Key": {created.Key}}
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "Scope allows", method: http.MethodPost, path: "/link", body: `{"url":"https://example.com"}`, status: http.StatusCreated},
+		{name: "Missing scope", method: http.MethodGet, path: "/stat?from=2026-01-01&to=2026-01-02", status: http.StatusForbidden},
+		{name: "Session only", method: http.MethodGet, path: "/users/me", status: http.StatusForbidden},
+		{name: "Keys can not manage keys", method: http.MethodGet, path: "/api-keys", status: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := send(tc.method, tc.path, tc.body, key)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	badKey := http.Header{"X-Api-Key": {"ak_unknown"}}
+	for _, path := range []string{"/healthz", "/openapi.json"} {
+		if w := send(http.MethodGet, path, "", badKey); w.Code != http.StatusOK {
+			t.Errorf("Expected public %s to ignore a bad key, got %d", path, w.Code)
+		}
+	}
+	if w := send(http.MethodGet, "/link", "", badKey); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected bad key to get %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	w = send(http.MethodGet, "/api-keys", "", bearer)
+	var list apikey.ListApiKeysResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Items) != 1 || list.Items[0].LastUsedAt == nil {
+		t.Fatalf("Expected one key with last used time, got %+v", list.Items)
+	}
+	if strings.Contains(w.Body.String(), created.Key) {
+		t.Error("Expected list to hide the key")
+	}
+
+	w = send(http.MethodDelete, "/api-keys/"+strconv.Itoa(int(created.Id)), "", bearer)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	w = send(http.MethodGet, "/link", "", key)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked key to get %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
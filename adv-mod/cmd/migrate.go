@@ -0,0 +1,141 @@
+package main
+
+import (
+	"adv-mod/configs"
+	"adv-mod/migrations"
+	"adv-mod/pkg/db"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage: adv-mod migrate <command> [flags]
+
+commands:
+  up             apply all pending migrations
+  down           revert the last applied migration
+  status         list migrations and whether they are applied
+  create <name>  create empty up/down files in -dir (default "migrations")
+
+up, down and status accept the same config flags as the server`
+
+// migrate выполняет sub-command `adv-mod migrate ...`
+func migrate(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+	if command == "create" {
+		return createMigration(args, out)
+	}
+	switch command {
+	case "up", "down", "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", command, migrateUsage)
+	}
+
+	conf, err := configs.Load(args)
+	if err != nil {
+		return err
+	}
+	database, err := db.NewDb(ctx, conf)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	migrator, err := db.NewMigrator(database, migrations.FS)
+	if err != nil {
+		return err
+	}
+	return runMigrate(ctx, migrator, command, out)
+}
+
+func runMigrate(ctx context.Context, migrator *db.Migrator, command string, out io.Writer) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+	case "down":
+		migration, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "reverted %d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Modified {
+				appliedAt += " (modified)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+func createMigration(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := fs.String("dir", "migrations", "directory with migration files")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(migrateUsage)
+	}
+	upPath, downPath, err := db.CreateMigration(*dir, fs.Arg(0), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "created %s\ncreated %s\n", upPath, downPath)
+	return nil
+}
+
+// migrateOnStart применяет миграции перед запуском сервера. Несколько экземпляров,
+// стартующих одновременно, сериализуются блокировкой Migrator. Без AutoMigrate
+// отстающая схема - ошибка: сервер с ней отвечал бы 500 на каждый запрос
+func migrateOnStart(ctx context.Context, conf configs.DbConfig, database *db.Db, source fs.FS) error {
+	migrator, err := db.NewMigrator(database, source)
+	if err != nil {
+		return err
+	}
+	if !conf.AutoMigrate {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("database schema is behind by %d migrations, run `adv-mod migrate up` or enable auto_migrate", pending)
+		}
+		return nil
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		slog.Info("migration applied", "version", migration.Version, "name", migration.Name)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"adv-mod/configs"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrateCreate(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := migrate(context.Background(), []string{"create", "-dir", dir, "add_links"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*_add_links.*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected up and down files, got %v", files)
+	}
+	if !strings.Contains(out.String(), files[0]) {
+		t.Errorf("Expected created files in output, got %q", out.String())
+	}
+	if _, err := os.Stat(files[1]); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigrateUsage(t *testing.T) {
+	testCases := [][]string{
+		{},
+		{"sideways"},
+		{"create"},
+	}
+	for _, args := range testCases {
+		err := migrate(context.Background(), args, &bytes.Buffer{})
+		if err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+func TestMigrateOnStart(t *testing.T) {
+	source := fstest.MapFS{
+		"20261018120000_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);")},
+		"20261018120000_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+	}
+	ctx := context.Background()
+
+	database := newTestDb(t)
+	err := migrateOnStart(ctx, configs.DbConfig{AutoMigrate: false}, database, source)
+	if err == nil {
+		t.Fatal("Expected pending migrations to stop the start without auto_migrate")
+	}
+	if err := migrateOnStart(ctx, configs.DbConfig{AutoMigrate: true}, database, source); err != nil {
+		t.Fatalf("Unexpected migration error: %v", err)
+	}
+	if !database.DB.Migrator().HasTable("widgets") {
+		t.Error("Expected migrations to be applied on start")
+	}
+	if err := migrateOnStart(ctx, configs.DbConfig{AutoMigrate: false}, database, source); err != nil {
+		t.Errorf("Expected up to date schema to start, got %v", err)
+	}
+}
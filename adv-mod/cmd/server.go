@@ -0,0 +1,98 @@
+package main
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/db"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+)
+
+func newServer(conf configs.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           conf.Address(),
+		Handler:        handler,
+		ReadTimeout:    conf.ReadTimeout,
+		WriteTimeout:   conf.WriteTimeout,
+		IdleTimeout:    conf.IdleTimeout,
+		MaxHeaderBytes: conf.MaxHeaderBytes,
+	}
+}
+
+// run запускает приложение и блокируется до отмены ctx, после чего дожидается
+// текущих запросов, затем обработки событий и фоновых задач, закрывает их
+// ресурсы и пул соединений с БД.
+// Если listener равен nil, слушается адрес из конфигурации
+func run(ctx context.Context, conf *configs.Config, database *db.Db, listener net.Listener) (err error) {
+	defer func() {
+		err = errors.Join(err, database.Close())
+	}()
+
+	app, err := App(conf, database)
+	if err != nil {
+		return err
+	}
+	// Шина и задачи останавливаются после сервера, чтобы обработать события последних запросов
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.Server.ShutdownTimeout)
+		defer cancel()
+		err = errors.Join(err, app.EventBus.Shutdown(shutdownCtx))
+		stopWorkers()
+		workers.Wait()
+		for _, closer := range app.Closers {
+			err = errors.Join(err, closer.Close())
+		}
+	}()
+	for _, worker := range app.Workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker(workersCtx)
+		}()
+	}
+	if listener == nil {
+		listener, err = net.Listen("tcp", conf.Server.Address())
+		if err != nil {
+			return err
+		}
+	}
+	server := newServer(conf.Server, app.Handler)
+	return serve(ctx, server, listener, conf.Server)
+}
+
+func serve(ctx context.Context, server *http.Server, listener net.Listener, conf configs.ServerConfig) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server is listening", "address", listener.Addr().String(), "tls", conf.IsTLS())
+		if conf.IsTLS() {
+			serveErr <- server.ServeTLS(listener, conf.TLSCertFile, conf.TLSKeyFile)
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", conf.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		// Не успели дождаться запросов - обрываем оставшиеся соединения
+		return errors.Join(err, server.Close())
+	}
+	err = <-serveErr
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -1,36 +1,413 @@
 package configs
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Db   DbConfig
-	Auth AuthConfig
+	Server    ServerConfig    `yaml:"server"`
+	Db        DbConfig        `yaml:"db"`
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Mail      MailConfig      `yaml:"mail"`
+	Audit     AuditConfig     `yaml:"audit"`
 }
 
+type ServerConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	MaxHeaderBytes  int           `yaml:"max_header_bytes"`
+	TLSCertFile     string        `yaml:"tls_cert_file"`
+	TLSKeyFile      string        `yaml:"tls_key_file"`
+	// SwaggerUI публикует страницу /docs со Swagger UI поверх /openapi.json
+	SwaggerUI bool `yaml:"swagger_ui"`
+}
+
+// DbConfig - нулевые значения пула означают значения database/sql по умолчанию
 type DbConfig struct {
-	Dsn string
+	Dsn             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectAttempts попыток подключения при старте с экспоненциальной паузой от ConnectBackoff
+	ConnectAttempts int           `yaml:"connect_attempts"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff"`
+	// AutoMigrate применяет миграции при старте. Без него сервер не стартует,
+	// пока схема отстаёт, и миграции применяет `adv-mod migrate up`
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
 
 type AuthConfig struct {
-	Secret string
+	Secret           string        `yaml:"secret"`
+	AccessTTL        time.Duration `yaml:"access_ttl"`
+	RefreshTTL       time.Duration `yaml:"refresh_ttl"`
+	VerifyEmailTTL   time.Duration `yaml:"verify_email_ttl"`
+	ResetPasswordTTL time.Duration `yaml:"reset_password_ttl"`
+	// AdminEmails получают роль admin при старте, если email подтверждён
+	AdminEmails []string `yaml:"admin_emails"`
 }
 
-func LoadConfig() *Config {
-	err := godotenv.Load("C:/Users/Sphirium/go/src/adv-mod/.env")
-	if err != nil {
-		log.Println("Error loading .env file, using default config")
-	}
+// MailConfig - без SMTPHost письма складываются в OutboxDir
+type MailConfig struct {
+	SMTPHost  string `yaml:"smtp_host"`
+	SMTPPort  int    `yaml:"smtp_port"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+	From      string `yaml:"from"`
+	OutboxDir string `yaml:"outbox_dir"`
+	// BaseURL - публичный адрес API. Ссылки в письмах ведут на его
+	// GET /auth/verify-email и GET /auth/reset-password
+	BaseURL string `yaml:"base_url"`
+}
+
+// RateLimitConfig задаёт защиту от перебора паролей. Нулевые значения отключают ограничение
+type RateLimitConfig struct {
+	AuthIpRequests     int           `yaml:"auth_ip_requests"`
+	AuthIpPer          time.Duration `yaml:"auth_ip_per"`
+	LoginEmailRequests int           `yaml:"login_email_requests"`
+	LoginEmailPer      time.Duration `yaml:"login_email_per"`
+	MaxFailedLogins    int           `yaml:"max_failed_logins"`
+	LockoutBase        time.Duration `yaml:"lockout_base"`
+	LockoutMax         time.Duration `yaml:"lockout_max"`
+}
+
+const (
+	AuditSinkDb   = "db"
+	AuditSinkFile = "file"
+)
+
+// AuditConfig - куда пишется журнал аудита. Sink file - JSON lines для окружений без БД,
+// тогда GET /audit недоступен
+type AuditConfig struct {
+	Sink string `yaml:"sink"`
+	File string `yaml:"file"`
+}
+
+const defaultEnvFile = ".env"
+
+// Default - нижний слой конфигурации, всё остальное его перекрывает
+func Default() *Config {
 	return &Config{
+		Server: ServerConfig{
+			Port:            8081,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			MaxHeaderBytes:  1 << 20,
+		},
 		Db: DbConfig{
-			Dsn: os.Getenv("DSN"),
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Second,
+			AutoMigrate:     true,
 		},
 		Auth: AuthConfig{
-			Secret: os.Getenv("TOKEN"),
+			AccessTTL:        15 * time.Minute,
+			RefreshTTL:       30 * 24 * time.Hour,
+			VerifyEmailTTL:   24 * time.Hour,
+			ResetPasswordTTL: time.Hour,
+		},
+		Mail: MailConfig{
+			SMTPPort:  587,
+			From:      "no-reply@localhost",
+			OutboxDir: "outbox",
+			BaseURL:   "http://localhost:8081",
+		},
+		RateLimit: RateLimitConfig{
+			AuthIpRequests:     20,
+			AuthIpPer:          time.Minute,
+			LoginEmailRequests: 5,
+			LoginEmailPer:      time.Minute,
+			MaxFailedLogins:    5,
+			LockoutBase:        time.Minute,
+			LockoutMax:         time.Hour,
+		},
+		Audit: AuditConfig{
+			Sink: AuditSinkDb,
+			File: "audit.log",
 		},
 	}
 }
+
+// LoadConfig читает конфигурацию из аргументов процесса и завершает его, если она невалидна
+func LoadConfig() *Config {
+	conf, err := Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	return conf
+}
+
+// Load собирает конфигурацию слоями: значения по умолчанию, файл YAML/JSON из -config,
+// .env из рабочей директории или -env, переменные окружения и, наконец, флаги
+func Load(args []string) (*Config, error) {
+	conf := Default()
+
+	fs := flag.NewFlagSet("adv-mod", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to YAML or JSON config file")
+	envPath := fs.String("env", defaultEnvFile, "path to .env file")
+	host := fs.String("host", conf.Server.Host, "HTTP server host")
+	port := fs.Int("port", conf.Server.Port, "HTTP server port")
+	dsn := fs.String("dsn", "", "database DSN")
+	readTimeout := fs.Duration("read-timeout", conf.Server.ReadTimeout, "HTTP server read timeout")
+	writeTimeout := fs.Duration("write-timeout", conf.Server.WriteTimeout, "HTTP server write timeout")
+	idleTimeout := fs.Duration("idle-timeout", conf.Server.IdleTimeout, "HTTP server idle timeout")
+	shutdownTimeout := fs.Duration("shutdown-timeout", conf.Server.ShutdownTimeout, "time to drain in-flight requests on shutdown")
+	tlsCert := fs.String("tls-cert", "", "path to TLS certificate")
+	tlsKey := fs.String("tls-key", "", "path to TLS private key")
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	isSet := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		isSet[f.Name] = true
+	})
+
+	if *configPath != "" {
+		err = loadFile(conf, *configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dotenv, err := godotenv.Read(*envPath)
+	if err != nil {
+		// Отсутствие .env допустимо, пока путь к нему не указан явно
+		if isSet["env"] || !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read %s: %w", *envPath, err)
+		}
+		dotenv = map[string]string{}
+	}
+	lookup := func(key string) (string, bool) {
+		if value, ok := os.LookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := dotenv[key]
+		return value, ok
+	}
+	err = applyEnv(conf, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	if isSet["host"] {
+		conf.Server.Host = *host
+	}
+	if isSet["port"] {
+		conf.Server.Port = *port
+	}
+	if isSet["dsn"] {
+		conf.Db.Dsn = *dsn
+	}
+	if isSet["read-timeout"] {
+		conf.Server.ReadTimeout = *readTimeout
+	}
+	if isSet["write-timeout"] {
+		conf.Server.WriteTimeout = *writeTimeout
+	}
+	if isSet["idle-timeout"] {
+		conf.Server.IdleTimeout = *idleTimeout
+	}
+	if isSet["shutdown-timeout"] {
+		conf.Server.ShutdownTimeout = *shutdownTimeout
+	}
+	if isSet["tls-cert"] {
+		conf.Server.TLSCertFile = *tlsCert
+	}
+	if isSet["tls-key"] {
+		conf.Server.TLSKeyFile = *tlsKey
+	}
+
+	err = conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// loadFile читает YAML. JSON является подмножеством YAML, поэтому разбирается тем же способом
+func loadFile(conf *Config, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .json", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = yaml.Unmarshal(data, conf)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func applyEnv(conf *Config, lookup func(string) (string, bool)) error {
+	if value, ok := lookup("DSN"); ok {
+		conf.Db.Dsn = value
+	}
+	if value, ok := lookup("TOKEN"); ok {
+		conf.Auth.Secret = value
+	}
+	if value, ok := lookup("ADMIN_EMAILS"); ok {
+		conf.Auth.AdminEmails = splitList(value)
+	}
+	strs := map[string]*string{
+		"HOST":          &conf.Server.Host,
+		"TLS_CERT_FILE": &conf.Server.TLSCertFile,
+		"TLS_KEY_FILE":  &conf.Server.TLSKeyFile,
+		"SMTP_HOST":     &conf.Mail.SMTPHost,
+		"SMTP_USERNAME": &conf.Mail.Username,
+		"SMTP_PASSWORD": &conf.Mail.Password,
+		"MAIL_FROM":     &conf.Mail.From,
+		"BASE_URL":      &conf.Mail.BaseURL,
+		"AUDIT_SINK":    &conf.Audit.Sink,
+		"AUDIT_FILE":    &conf.Audit.File,
+	}
+	for key, target := range strs {
+		if value, ok := lookup(key); ok {
+			*target = value
+		}
+	}
+	ints := map[string]*int{
+		"PORT":                &conf.Server.Port,
+		"MAX_HEADER_BYTES":    &conf.Server.MaxHeaderBytes,
+		"SMTP_PORT":           &conf.Mail.SMTPPort,
+		"DB_MAX_OPEN_CONNS":   &conf.Db.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS":   &conf.Db.MaxIdleConns,
+		"DB_CONNECT_ATTEMPTS": &conf.Db.ConnectAttempts,
+	}
+	for key, target := range ints {
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*target = n
+	}
+	bools := map[string]*bool{
+		"SWAGGER_UI":      &conf.Server.SwaggerUI,
+		"DB_AUTO_MIGRATE": &conf.Db.AutoMigrate,
+	}
+	for key, target := range bools {
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*target = b
+	}
+	durations := map[string]*time.Duration{
+		"READ_TIMEOUT":          &conf.Server.ReadTimeout,
+		"WRITE_TIMEOUT":         &conf.Server.WriteTimeout,
+		"IDLE_TIMEOUT":          &conf.Server.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":      &conf.Server.ShutdownTimeout,
+		"ACCESS_TTL":            &conf.Auth.AccessTTL,
+		"REFRESH_TTL":           &conf.Auth.RefreshTTL,
+		"VERIFY_EMAIL_TTL":      &conf.Auth.VerifyEmailTTL,
+		"RESET_TTL":             &conf.Auth.ResetPasswordTTL,
+		"DB_CONN_MAX_LIFETIME":  &conf.Db.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME": &conf.Db.ConnMaxIdleTime,
+		"DB_CONNECT_BACKOFF":    &conf.Db.ConnectBackoff,
+	}
+	for key, target := range durations {
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*target = duration
+	}
+	return nil
+}
+
+func (conf *Config) Validate() error {
+	var errs []error
+	if conf.Db.Dsn == "" {
+		errs = append(errs, errors.New("database DSN is required (DSN)"))
+	}
+	if conf.Db.MaxOpenConns < 0 || conf.Db.MaxIdleConns < 0 || conf.Db.ConnMaxLifetime < 0 || conf.Db.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("database pool settings must not be negative"))
+	}
+	if conf.Db.ConnectAttempts < 1 || conf.Db.ConnectBackoff < 0 {
+		errs = append(errs, errors.New("database connect attempts must be positive and backoff not negative"))
+	}
+	if conf.Auth.Secret == "" {
+		errs = append(errs, errors.New("auth secret is required (TOKEN)"))
+	}
+	if conf.Server.Port < 1 || conf.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server port %d is out of range", conf.Server.Port))
+	}
+	if conf.Server.ReadTimeout < 0 || conf.Server.WriteTimeout < 0 || conf.Server.IdleTimeout < 0 || conf.Server.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if conf.Auth.AccessTTL <= 0 || conf.Auth.RefreshTTL <= 0 || conf.Auth.VerifyEmailTTL <= 0 || conf.Auth.ResetPasswordTTL <= 0 {
+		errs = append(errs, errors.New("auth token TTLs must be positive"))
+	}
+	if conf.RateLimit.AuthIpRequests < 0 || conf.RateLimit.LoginEmailRequests < 0 || conf.RateLimit.MaxFailedLogins < 0 {
+		errs = append(errs, errors.New("rate limits must not be negative"))
+	}
+	if conf.Server.MaxHeaderBytes < 0 {
+		errs = append(errs, errors.New("server max header bytes must not be negative"))
+	}
+	if (conf.Server.TLSCertFile == "") != (conf.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS requires both certificate and key files"))
+	}
+	if conf.Audit.Sink != AuditSinkDb && conf.Audit.Sink != AuditSinkFile {
+		errs = append(errs, fmt.Errorf("audit sink must be %s or %s, got %q", AuditSinkDb, AuditSinkFile, conf.Audit.Sink))
+	}
+	if conf.Audit.Sink == AuditSinkFile && conf.Audit.File == "" {
+		errs = append(errs, errors.New("audit file is required for the file sink (AUDIT_FILE)"))
+	}
+	return errors.Join(errs...)
+}
+
+func (server ServerConfig) Address() string {
+	return net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+}
+
+func (server ServerConfig) IsTLS() bool {
+	return server.TLSCertFile != "" && server.TLSKeyFile != ""
+}
+
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
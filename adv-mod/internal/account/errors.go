@@ -0,0 +1,9 @@
+package account
+
+import "errors"
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrWrongPassword = errors.New("wrong current password")
+	ErrEmailTaken    = errors.New("email is already taken")
+)
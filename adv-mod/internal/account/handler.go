@@ -0,0 +1,147 @@
+package account
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/audit"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/request"
+	"adv-mod/pkg/response"
+	"errors"
+	"net/http"
+)
+
+type AccountHandlerDeps struct {
+	*configs.Config
+	AccountService *AccountService
+	Audit          *audit.Recorder
+	OpenAPI        *openapi.Registry
+}
+
+type AccountHandler struct {
+	*configs.Config
+	AccountService *AccountService
+	Audit          *audit.Recorder
+}
+
+// NewHelloHandler регистрирует маршруты текущего пользователя /users/me. API ключам они недоступны
+func NewHelloHandler(router *http.ServeMux, deps AccountHandlerDeps) {
+	handler := &AccountHandler{
+		Config:         deps.Config,
+		AccountService: deps.AccountService,
+		Audit:          deps.Audit,
+	}
+	api := deps.OpenAPI
+	failure := response.ErrorBody{}
+	api.Handle(router, "GET /users/me", middleware.IsAuthed(middleware.SessionOnly(handler.Profile()), deps.Config), openapi.Operation{
+		Summary:   "Get the profile of the current user",
+		Tags:      []string{"account"},
+		Responses: map[int]any{200: ProfileResponse{}, 401: failure, 404: failure},
+		Security:  []string{openapi.SecurityBearer},
+	})
+	api.Handle(router, "PATCH /users/me", middleware.IsAuthed(middleware.SessionOnly(handler.UpdateProfile()), deps.Config), openapi.Operation{
+		Summary:     "Update name or email of the current user",
+		Description: "A new email has to be verified again, a verification link is sent to it.",
+		Tags:        []string{"account"},
+		Request:     UpdateProfileRequest{},
+		Responses:   map[int]any{200: ProfileResponse{}, 400: failure, 401: failure, 404: failure, 409: failure, 422: failure},
+		Security:    []string{openapi.SecurityBearer},
+	})
+	api.Handle(router, "POST /users/me/password", middleware.IsAuthed(middleware.SessionOnly(handler.ChangePassword()), deps.Config), openapi.Operation{
+		Summary:     "Change the password of the current user",
+		Description: "All refresh tokens of the user are revoked.",
+		Tags:        []string{"account"},
+		Request:     ChangePasswordRequest{},
+		Responses:   map[int]any{204: nil, 400: failure, 401: failure, 403: failure, 404: failure, 422: failure},
+		Security:    []string{openapi.SecurityBearer},
+	})
+	api.Handle(router, "DELETE /users/me", middleware.IsAuthed(middleware.SessionOnly(handler.Delete()), deps.Config), openapi.Operation{
+		Summary:   "Delete the current user and revoke all sessions",
+		Tags:      []string{"account"},
+		Responses: map[int]any{204: nil, 401: failure, 404: failure},
+		Security:  []string{openapi.SecurityBearer},
+	})
+}
+
+func (handler *AccountHandler) Profile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		u, err := handler.AccountService.Profile(userId)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		response.Json(w, NewProfileResponse(u), http.StatusOK)
+	}
+}
+
+func (handler *AccountHandler) UpdateProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		body, err := request.HandleBody[UpdateProfileRequest](&w, r)
+		if err != nil {
+			return
+		}
+		u, err := handler.AccountService.UpdateProfile(userId, body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		response.Json(w, NewProfileResponse(u), http.StatusOK)
+	}
+}
+
+func (handler *AccountHandler) ChangePassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		body, err := request.HandleBody[ChangePasswordRequest](&w, r)
+		if err != nil {
+			return
+		}
+		err = handler.AccountService.ChangePassword(userId, body.CurrentPassword, body.NewPassword)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{Action: audit.ActionPasswordChange})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (handler *AccountHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		err := handler.AccountService.Delete(userId)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{Action: audit.ActionUserDelete, TargetId: userId})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		response.NotFound(w, err.Error())
+	case errors.Is(err, ErrEmailTaken):
+		response.Conflict(w, err.Error())
+	case errors.Is(err, ErrWrongPassword):
+		response.Forbidden(w, err.Error())
+	default:
+		response.InternalServerError(w, err)
+	}
+}
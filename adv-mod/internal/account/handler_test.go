@@ -0,0 +1,300 @@
+package account_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/account"
+	"adv-mod/internal/dbtest"
+	"adv-mod/internal/session"
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/rbac"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	testSecret   = "secret"
+	testPassword = "Passw0rd!"
+)
+
+// MockVerifier запоминает адреса, на которые ушло письмо подтверждения
+type MockVerifier struct {
+	sent []string
+}
+
+func (verifier *MockVerifier) SendVerificationEmail(u *user.User) error {
+	verifier.sent = append(verifier.sent, u.Email)
+	return nil
+}
+
+type testEnv struct {
+	users    *user.UserRepository
+	sessions *session.RefreshTokenRepository
+	tokens   *verification.TokenRepository
+	verifier *MockVerifier
+	router   *http.ServeMux
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	database := dbtest.Open(t, &user.User{}, &session.RefreshToken{}, &verification.Token{})
+	env := &testEnv{
+		users:    user.NewUserRepository(database),
+		sessions: session.NewRefreshTokenRepository(database),
+		tokens:   verification.NewTokenRepository(database),
+		verifier: &MockVerifier{},
+		router:   http.NewServeMux(),
+	}
+	account.NewHelloHandler(env.router, account.AccountHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+		},
+		AccountService: account.NewAccountService(account.AccountServiceDeps{
+			UserRepository:         env.users,
+			RefreshTokenRepository: env.sessions,
+			Verifier:               env.verifier,
+		}),
+	})
+	return env
+}
+
+func (env *testEnv) createUser(t *testing.T, email string) *user.User {
+	t.Helper()
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	u, err := env.users.Create(&user.User{
+		Email:           email,
+		Password:        string(hashedPassword),
+		Name:            "Вася",
+		Role:            rbac.RoleUser,
+		EmailVerifiedAt: &now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.sessions.Create(&session.RefreshToken{
+		UserId:    u.ID,
+		FamilyId:  "family",
+		TokenHash: "hash-" + email,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func (env *testEnv) serve(u *user.User, method, path string, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: u.Email, UserId: u.ID, Role: u.Role})
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+func (env *testEnv) sessionRevoked(t *testing.T, email string) bool {
+	t.Helper()
+	stored, err := env.sessions.FindByHash("hash-" + email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored.RevokedAt != nil
+}
+
+func TestProfile(t *testing.T) {
+	env := newTestEnv(t)
+	u := env.createUser(t, "a@a.ru")
+
+	w := env.serve(u, http.MethodGet, "/users/me", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp account.ProfileResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Id != u.ID || resp.Email != "a@a.ru" || !resp.EmailVerified {
+		t.Errorf("Unexpected profile %+v", resp)
+	}
+}
+
+func TestProfileRequiresUserId(t *testing.T) {
+	env := newTestEnv(t)
+	u := env.createUser(t, "a@a.ru")
+	u.ID = 0
+
+	w := env.serve(u, http.MethodGet, "/users/me", nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		payload  map[string]any
+		status   int
+		email    string
+		verified bool
+	}{
+		{name: "Name", payload: map[string]any{"name": "Петя"}, status: http.StatusOK, email: "a@a.ru", verified: true},
+		{name: "Same email", payload: map[string]any{"email": "a@a.ru"}, status: http.StatusOK, email: "a@a.ru", verified: true},
+		{name: "New email", payload: map[string]any{"email": "new@a.ru"}, status: http.StatusOK, email: "new@a.ru", verified: false},
+		{name: "Taken email", payload: map[string]any{"email": "b@a.ru"}, status: http.StatusConflict, email: "a@a.ru", verified: true},
+		{name: "Email of deleted user", payload: map[string]any{"email": "deleted@a.ru"}, status: http.StatusOK, email: "deleted@a.ru", verified: false},
+		{name: "Invalid email", payload: map[string]any{"email": "not-email"}, status: http.StatusUnprocessableEntity, email: "a@a.ru", verified: true},
+		{name: "Empty name", payload: map[string]any{"name": ""}, status: http.StatusUnprocessableEntity, email: "a@a.ru", verified: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			u := env.createUser(t, "a@a.ru")
+			env.createUser(t, "b@a.ru")
+			deleted := env.createUser(t, "deleted@a.ru")
+			if err := env.users.Delete(deleted.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			w := env.serve(u, http.MethodPatch, "/users/me", tc.payload)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			stored, err := env.users.FindById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Email != tc.email {
+				t.Errorf("Expected email %s, got %s", tc.email, stored.Email)
+			}
+			if (stored.EmailVerifiedAt != nil) != tc.verified {
+				t.Errorf("Expected verified %v, got %v", tc.verified, stored.EmailVerifiedAt)
+			}
+			if name, ok := tc.payload["name"].(string); ok && tc.status == http.StatusOK && stored.Name != name {
+				t.Errorf("Expected name %s, got %s", name, stored.Name)
+			}
+			if sent := len(env.verifier.sent) == 1 && env.verifier.sent[0] == tc.email; sent != !tc.verified {
+				t.Errorf("Unexpected verification emails %v", env.verifier.sent)
+			}
+		})
+	}
+}
+
+func TestUpdateEmailInvalidatesLinks(t *testing.T) {
+	env := newTestEnv(t)
+	u := env.createUser(t, "a@a.ru")
+	for _, purpose := range []string{verification.PurposeVerifyEmail, verification.PurposeResetPassword} {
+		_, err := env.tokens.Create(&verification.Token{
+			UserId:    u.ID,
+			Purpose:   purpose,
+			Email:     u.Email,
+			TokenHash: "hash-" + purpose,
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := env.serve(u, http.MethodPatch, "/users/me", map[string]any{"email": "new@a.ru"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	for _, purpose := range []string{verification.PurposeVerifyEmail, verification.PurposeResetPassword} {
+		stored, err := env.tokens.FindByHash("hash-"+purpose, purpose)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.UsedAt == nil {
+			t.Errorf("Expected %s link to the old email to be invalidated", purpose)
+		}
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	testCases := []struct {
+		name    string
+		current string
+		status  int
+	}{
+		{name: "Success", current: testPassword, status: http.StatusNoContent},
+		{name: "Wrong current password", current: "Wr0ngPass!", status: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			u := env.createUser(t, "a@a.ru")
+
+			w := env.serve(u, http.MethodPost, "/users/me/password", map[string]string{
+				"current_password": tc.current,
+				"new_password":     "N3wPassword!",
+			})
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			stored, err := env.users.FindById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed := bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("N3wPassword!")) == nil
+			success := tc.status == http.StatusNoContent
+			if changed != success {
+				t.Errorf("Expected password changed %v, got %v", success, changed)
+			}
+			if revoked := env.sessionRevoked(t, u.Email); revoked != success {
+				t.Errorf("Expected sessions revoked %v, got %v", success, revoked)
+			}
+		})
+	}
+}
+
+func TestChangePasswordRejectsWeakPassword(t *testing.T) {
+	env := newTestEnv(t)
+	u := env.createUser(t, "a@a.ru")
+
+	w := env.serve(u, http.MethodPost, "/users/me/password", map[string]string{
+		"current_password": testPassword,
+		"new_password":     "weak",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	env := newTestEnv(t)
+	u := env.createUser(t, "a@a.ru")
+
+	w := env.serve(u, http.MethodDelete, "/users/me", nil)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	_, err := env.users.FindById(u.ID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected user to be deleted, got %v", err)
+	}
+	if !env.sessionRevoked(t, u.Email) {
+		t.Error("Expected sessions to be revoked")
+	}
+
+	w = env.serve(u, http.MethodGet, "/users/me", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected %d after delete, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package account
+
+import (
+	"adv-mod/internal/user"
+	"time"
+)
+
+type ProfileResponse struct {
+	Id            uint      `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UpdateProfileRequest - частичное обновление, отсутствующие поля не меняются
+type UpdateProfileRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=1"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,password"`
+}
+
+func NewProfileResponse(u *user.User) ProfileResponse {
+	return ProfileResponse{
+		Id:            u.ID,
+		Email:         u.Email,
+		Name:          u.Name,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		CreatedAt:     u.CreatedAt,
+	}
+}
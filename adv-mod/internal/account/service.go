@@ -0,0 +1,121 @@
+package account
+
+import (
+	"adv-mod/internal/user"
+	"adv-mod/pkg/di"
+	"errors"
+	"log/slog"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Verifier отправляет письмо подтверждения email, реализуется auth.AuthService
+type Verifier interface {
+	SendVerificationEmail(u *user.User) error
+}
+
+type AccountServiceDeps struct {
+	UserRepository         di.IUserRepository
+	RefreshTokenRepository di.IRefreshTokenRepository
+	Verifier               Verifier
+}
+
+type AccountService struct {
+	UserRepository         di.IUserRepository
+	RefreshTokenRepository di.IRefreshTokenRepository
+	Verifier               Verifier
+}
+
+func NewAccountService(deps AccountServiceDeps) *AccountService {
+	return &AccountService{
+		UserRepository:         deps.UserRepository,
+		RefreshTokenRepository: deps.RefreshTokenRepository,
+		Verifier:               deps.Verifier,
+	}
+}
+
+func (service *AccountService) Profile(userId uint) (*user.User, error) {
+	u, err := service.UserRepository.FindById(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+// UpdateProfile меняет имя и email. Новый email считается неподтверждённым,
+// на него уходит письмо подтверждения
+func (service *AccountService) UpdateProfile(userId uint, body *UpdateProfileRequest) (*user.User, error) {
+	u, err := service.Profile(userId)
+	if err != nil {
+		return nil, err
+	}
+	if body.Name != nil && *body.Name != u.Name {
+		u.Name = *body.Name
+		_, err = service.UserRepository.Update(&user.User{Model: u.Model, Name: u.Name})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if body.Email == nil || *body.Email == u.Email {
+		return u, nil
+	}
+	existedUser, err := service.UserRepository.FindByEmail(*body.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existedUser != nil {
+		return nil, ErrEmailTaken
+	}
+	err = service.UserRepository.ChangeEmail(u.ID, *body.Email)
+	// Уникальный индекс по email ловит гонку с регистрацией
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Email = *body.Email
+	u.EmailVerifiedAt = nil
+	if service.Verifier != nil {
+		if err := service.Verifier.SendVerificationEmail(u); err != nil {
+			slog.Error("send verification email", "error", err, "user_id", u.ID)
+		}
+	}
+	return u, nil
+}
+
+// ChangePassword проверяет текущий пароль и завершает все сессии пользователя
+func (service *AccountService) ChangePassword(userId uint, currentPassword, newPassword string) error {
+	u, err := service.Profile(userId)
+	if err != nil {
+		return err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(currentPassword))
+	if err != nil {
+		return ErrWrongPassword
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = service.UserRepository.Update(&user.User{Model: u.Model, Password: string(hashedPassword)})
+	if err != nil {
+		return err
+	}
+	return service.RefreshTokenRepository.RevokeUser(u.ID)
+}
+
+// Delete мягко удаляет пользователя и отзывает refresh токены. Выданные access
+// токены доживают свой TTL, но /users/me по ним уже отвечает 404
+func (service *AccountService) Delete(userId uint) error {
+	u, err := service.Profile(userId)
+	if err != nil {
+		return err
+	}
+	err = service.RefreshTokenRepository.RevokeUser(u.ID)
+	if err != nil {
+		return err
+	}
+	return service.UserRepository.Delete(u.ID)
+}
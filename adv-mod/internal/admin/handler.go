@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/audit"
+	"adv-mod/pkg/di"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/request"
+	"adv-mod/pkg/response"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type AdminHandlerDeps struct {
+	*configs.Config
+	UserRepository         di.IUserRepository
+	RefreshTokenRepository di.IRefreshTokenRepository
+	Audit                  *audit.Recorder
+	OpenAPI                *openapi.Registry
+}
+
+type AdminHandler struct {
+	*configs.Config
+	UserRepository         di.IUserRepository
+	RefreshTokenRepository di.IRefreshTokenRepository
+	Audit                  *audit.Recorder
+}
+
+// NewHelloHandler регистрирует админские маршруты. Роль берётся из access токена:
+// смена роли отзывает сессии пользователя, и выданный токен действует только до истечения
+func NewHelloHandler(router *http.ServeMux, deps AdminHandlerDeps) {
+	handler := &AdminHandler{
+		Config:                 deps.Config,
+		UserRepository:         deps.UserRepository,
+		RefreshTokenRepository: deps.RefreshTokenRepository,
+		Audit:                  deps.Audit,
+	}
+	canRead := middleware.RequirePermission(rbac.PermissionUsersRead)
+	canWrite := middleware.RequirePermission(rbac.PermissionUsersWrite)
+	api := deps.OpenAPI
+	failure := response.ErrorBody{}
+	api.Handle(router, "GET /admin/users", middleware.IsAuthed(canRead(handler.ListUsers()), deps.Config), openapi.Operation{
+		Summary: "List users",
+		Tags:    []string{"admin"},
+		Query: []openapi.Parameter{
+			{Name: "limit", Type: "integer", Description: "page size, up to " + strconv.Itoa(MaxLimit)},
+			{Name: "offset", Type: "integer"},
+		},
+		Responses: map[int]any{200: ListUsersResponse{}, 400: failure, 401: failure, 403: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	api.Handle(router, "PATCH /admin/users/{id}/role", middleware.IsAuthed(canWrite(handler.ChangeRole()), deps.Config), openapi.Operation{
+		Summary:     "Change the role of a user",
+		Description: "Roles: " + strings.Join(rbac.Roles(), ", ") + ". All sessions of the user are revoked.",
+		Tags:        []string{"admin"},
+		Request:     ChangeRoleRequest{},
+		Responses:   map[int]any{200: UserResponse{}, 400: failure, 401: failure, 403: failure, 404: failure, 422: failure},
+		Security:    []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+}
+
+func (handler *AdminHandler) ListUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := queryInt(r, "limit", DefaultLimit)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			response.BadRequest(w, "limit must be between 1 and "+strconv.Itoa(MaxLimit))
+			return
+		}
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			response.BadRequest(w, "offset must be a non-negative integer")
+			return
+		}
+		users, total, err := handler.UserRepository.List(limit, offset)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		data := ListUsersResponse{
+			Items:  make([]UserResponse, 0, len(users)),
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		}
+		for i := range users {
+			data.Items = append(data.Items, NewUserResponse(&users[i]))
+		}
+		response.Json(w, data, http.StatusOK)
+	}
+}
+
+func (handler *AdminHandler) ChangeRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(w, "invalid user id")
+			return
+		}
+		body, err := request.HandleBody[ChangeRoleRequest](&w, r)
+		if err != nil {
+			return
+		}
+		if !rbac.IsValidRole(body.Role) {
+			response.UnprocessableEntity(w, "role must be one of: "+strings.Join(rbac.Roles(), ", "), nil)
+			return
+		}
+		u, err := handler.UserRepository.FindById(uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(w, "user not found")
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		// Не даём админу случайно лишить себя доступа. Сверяем по id: email в токене
+		// мог устареть после смены адреса
+		if userId, _ := middleware.UserIdFromContext(r.Context()); userId == u.ID {
+			response.Forbidden(w, "can not change own role")
+			return
+		}
+		err = handler.UserRepository.UpdateRole(u.ID, body.Role)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		// Иначе пониженный пользователь получал бы прежнюю роль при обновлении токена
+		if u.Role != body.Role {
+			err = handler.RefreshTokenRepository.RevokeUser(u.ID)
+			if err != nil {
+				response.InternalServerError(w, err)
+				return
+			}
+		}
+		handler.Audit.Record(r, audit.Entry{
+			Action:   audit.ActionRoleChange,
+			TargetId: u.ID,
+			Details:  u.Role + " -> " + body.Role,
+		})
+		u.Role = body.Role
+		response.Json(w, NewUserResponse(u), http.StatusOK)
+	}
+}
+
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
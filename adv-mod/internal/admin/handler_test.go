@@ -0,0 +1,261 @@
+package admin_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/admin"
+	"adv-mod/internal/audit"
+	"adv-mod/internal/dbtest"
+	"adv-mod/internal/session"
+	"adv-mod/internal/user"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/rbac"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret = "secret"
+	// testAdminId - id админа из токена serve. Не совпадает ни с одним пользователем в базе
+	testAdminId = 1000
+)
+
+func newTestRepository(t *testing.T) *user.UserRepository {
+	t.Helper()
+	return user.NewUserRepository(dbtest.Open(t, &user.User{}, &session.RefreshToken{}))
+}
+
+func newTestRouter(repo *user.UserRepository) *http.ServeMux {
+	router := http.NewServeMux()
+	admin.NewHelloHandler(router, admin.AdminHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+		},
+		UserRepository:         repo,
+		RefreshTokenRepository: session.NewRefreshTokenRepository(repo.Database),
+	})
+	return router
+}
+
+func createUser(t *testing.T, repo *user.UserRepository, email, role string) *user.User {
+	t.Helper()
+	u, err := repo.Create(&user.User{Email: email, Name: email, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func serve(router http.Handler, method, path, role string, payload any) *httptest.ResponseRecorder {
+	return serveAs(router, jwt.JWTData{Email: "admin@a.ru", UserId: testAdminId, Role: role}, method, path, payload)
+}
+
+func serveAs(router http.Handler, claims jwt.JWTData, method, path string, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	token, _ := jwt.NewJWT(testSecret).Create(claims)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListUsers(t *testing.T) {
+	repo := newTestRepository(t)
+	router := newTestRouter(repo)
+	createUser(t, repo, "admin@a.ru", rbac.RoleAdmin)
+	createUser(t, repo, "b@a.ru", rbac.RoleUser)
+	createUser(t, repo, "c@a.ru", rbac.RoleUser)
+
+	w := serve(router, http.MethodGet, "/admin/users?limit=2&offset=1", rbac.RoleAdmin, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp admin.ListUsersResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 3 || len(resp.Items) != 2 {
+		t.Fatalf("Expected 2 of 3 users, got %d of %d", len(resp.Items), resp.Total)
+	}
+	if resp.Items[0].Email != "b@a.ru" {
+		t.Errorf("Expected b@a.ru first, got %q", resp.Items[0].Email)
+	}
+
+	w = serve(router, http.MethodGet, "/admin/users", rbac.RoleUser, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected %d for user role, got %d", http.StatusForbidden, w.Code)
+	}
+	w = serve(router, http.MethodGet, "/admin/users?limit=1000", rbac.RoleAdmin, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d for large limit, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestChangeRole(t *testing.T) {
+	repo := newTestRepository(t)
+	router := newTestRouter(repo)
+	other := createUser(t, repo, "b@a.ru", rbac.RoleUser)
+	path := func(id uint) string {
+		return "/admin/users/" + strconv.Itoa(int(id)) + "/role"
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		role   string
+		body   any
+		status int
+	}{
+		{name: "Promote", path: path(other.ID), role: rbac.RoleAdmin, body: admin.ChangeRoleRequest{Role: rbac.RoleAdmin}, status: http.StatusOK},
+		{name: "Unknown role", path: path(other.ID), role: rbac.RoleAdmin, body: admin.ChangeRoleRequest{Role: "root"}, status: http.StatusUnprocessableEntity},
+		{name: "Missing user", path: path(100), role: rbac.RoleAdmin, body: admin.ChangeRoleRequest{Role: rbac.RoleUser}, status: http.StatusNotFound},
+		{name: "Not admin", path: path(other.ID), role: rbac.RoleUser, body: admin.ChangeRoleRequest{Role: rbac.RoleUser}, status: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(router, http.MethodPatch, tc.path, tc.role, tc.body)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	u, err := repo.FindById(other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Role != rbac.RoleAdmin {
+		t.Errorf("Expected role %q, got %q", rbac.RoleAdmin, u.Role)
+	}
+}
+
+func TestChangeOwnRole(t *testing.T) {
+	repo := newTestRepository(t)
+	router := newTestRouter(repo)
+	self := createUser(t, repo, "new@a.ru", rbac.RoleAdmin)
+	// Старый адрес админа занял другой пользователь
+	other := createUser(t, repo, "old@a.ru", rbac.RoleUser)
+	claims := jwt.JWTData{Email: "old@a.ru", UserId: self.ID, Role: rbac.RoleAdmin}
+	path := func(id uint) string {
+		return "/admin/users/" + strconv.Itoa(int(id)) + "/role"
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "Own role after email change", path: path(self.ID), status: http.StatusForbidden},
+		{name: "User with the old email", path: path(other.ID), status: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveAs(router, claims, http.MethodPatch, tc.path, admin.ChangeRoleRequest{Role: rbac.RoleUser})
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	u, err := repo.FindById(self.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Role != rbac.RoleAdmin {
+		t.Errorf("Expected role %q, got %q", rbac.RoleAdmin, u.Role)
+	}
+}
+
+func TestPromoteAdmins(t *testing.T) {
+	repo := newTestRepository(t)
+	verifiedAt := time.Now()
+	verified, err := repo.Create(&user.User{Email: "a@a.ru", Role: rbac.RoleUser, EmailVerifiedAt: &verifiedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unverified := createUser(t, repo, "b@a.ru", rbac.RoleUser)
+
+	if err := repo.PromoteAdmins([]string{"a@a.ru", "b@a.ru"}); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := repo.FindById(verified.ID)
+	if u.Role != rbac.RoleAdmin {
+		t.Errorf("Expected verified user to be admin, got %q", u.Role)
+	}
+	u, _ = repo.FindById(unverified.ID)
+	if u.Role != rbac.RoleUser {
+		t.Errorf("Expected unverified user to stay user, got %q", u.Role)
+	}
+}
+
+type memorySink struct {
+	entries []audit.Entry
+}
+
+func (sink *memorySink) Write(entry *audit.Entry) error {
+	sink.entries = append(sink.entries, *entry)
+	return nil
+}
+
+func TestChangeRoleIsAudited(t *testing.T) {
+	repo := newTestRepository(t)
+	createUser(t, repo, "admin@a.ru", rbac.RoleAdmin)
+	target := createUser(t, repo, "b@a.ru", rbac.RoleUser)
+	sink := &memorySink{}
+	router := http.NewServeMux()
+	admin.NewHelloHandler(router, admin.AdminHandlerDeps{
+		Config:                 &configs.Config{Auth: configs.AuthConfig{Secret: testSecret}},
+		UserRepository:         repo,
+		RefreshTokenRepository: session.NewRefreshTokenRepository(repo.Database),
+		Audit:                  audit.NewRecorder(sink, nil),
+	})
+
+	path := "/admin/users/" + strconv.Itoa(int(target.ID)) + "/role"
+	serve(router, http.MethodPatch, path, rbac.RoleAdmin, map[string]string{"role": rbac.RoleAdmin})
+	serve(router, http.MethodPatch, path, rbac.RoleUser, map[string]string{"role": rbac.RoleAdmin})
+
+	if len(sink.entries) != 1 {
+		t.Fatalf("Expected only the allowed change to be audited, got %d entries", len(sink.entries))
+	}
+	entry := sink.entries[0]
+	if entry.Action != audit.ActionRoleChange || entry.ActorEmail != "admin@a.ru" || entry.TargetId != target.ID || entry.Details != "user -> admin" {
+		t.Errorf("Unexpected audit entry %+v", entry)
+	}
+}
+
+func TestChangeRoleRevokesSessions(t *testing.T) {
+	repo := newTestRepository(t)
+	router := newTestRouter(repo)
+	target := createUser(t, repo, "b@a.ru", rbac.RoleAdmin)
+	sessions := session.NewRefreshTokenRepository(repo.Database)
+	stored, err := sessions.Create(&session.RefreshToken{
+		UserId:    target.ID,
+		FamilyId:  "family",
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/admin/users/" + strconv.Itoa(int(target.ID)) + "/role"
+	w := serve(router, http.MethodPatch, path, rbac.RoleAdmin, admin.ChangeRoleRequest{Role: rbac.RoleUser})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	stored, err = sessions.FindByHash(stored.TokenHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == nil {
+		t.Error("Expected refresh token of the demoted user to be revoked")
+	}
+}
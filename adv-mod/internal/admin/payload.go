@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"adv-mod/internal/user"
+	"time"
+)
+
+type UserResponse struct {
+	Id            uint      `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ListUsersResponse struct {
+	Items  []UserResponse `json:"items"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+func NewUserResponse(u *user.User) UserResponse {
+	return UserResponse{
+		Id:            u.ID,
+		Email:         u.Email,
+		Name:          u.Name,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		CreatedAt:     u.CreatedAt,
+	}
+}
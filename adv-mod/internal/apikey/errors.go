@@ -0,0 +1,9 @@
+package apikey
+
+import "errors"
+
+var (
+	ErrKeyNotFound     = errors.New("api key not found")
+	ErrScopeNotAllowed = errors.New("scope is not allowed for your role")
+	ErrTooManyKeys     = errors.New("too many api keys, revoke unused ones")
+)
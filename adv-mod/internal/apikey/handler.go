@@ -0,0 +1,147 @@
+package apikey
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/audit"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/request"
+	"adv-mod/pkg/response"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type ApiKeyHandlerDeps struct {
+	*configs.Config
+	ApiKeyService *ApiKeyService
+	Audit         *audit.Recorder
+	OpenAPI       *openapi.Registry
+}
+
+type ApiKeyHandler struct {
+	*configs.Config
+	ApiKeyService *ApiKeyService
+	Audit         *audit.Recorder
+}
+
+// NewHelloHandler регистрирует управление API ключами текущего пользователя.
+// Сами ключи сюда доступа не дают, нужен access токен
+func NewHelloHandler(router *http.ServeMux, deps ApiKeyHandlerDeps) {
+	handler := &ApiKeyHandler{
+		Config:        deps.Config,
+		ApiKeyService: deps.ApiKeyService,
+		Audit:         deps.Audit,
+	}
+	api := deps.OpenAPI
+	failure := response.ErrorBody{}
+	api.Handle(router, "POST /api-keys", middleware.IsAuthed(middleware.SessionOnly(handler.Create()), deps.Config), openapi.Operation{
+		Summary: "Create an API key",
+		Description: "The key is returned only once, only its prefix is stored in the clear. " +
+			"Scopes are permissions of the current role, e.g. links:read, links:write, stat:read. " +
+			"Send the key in the " + middleware.APIKeyHeader + " header.",
+		Tags:      []string{"api-keys"},
+		Request:   CreateApiKeyRequest{},
+		Responses: map[int]any{201: CreateApiKeyResponse{}, 400: failure, 401: failure, 403: failure, 409: failure, 422: failure},
+		Security:  []string{openapi.SecurityBearer},
+	})
+	api.Handle(router, "GET /api-keys", middleware.IsAuthed(middleware.SessionOnly(handler.List()), deps.Config), openapi.Operation{
+		Summary:   "List API keys of the current user",
+		Tags:      []string{"api-keys"},
+		Responses: map[int]any{200: ListApiKeysResponse{}, 401: failure, 403: failure},
+		Security:  []string{openapi.SecurityBearer},
+	})
+	api.Handle(router, "DELETE /api-keys/{id}", middleware.IsAuthed(middleware.SessionOnly(handler.Revoke()), deps.Config), openapi.Operation{
+		Summary:   "Revoke an API key",
+		Tags:      []string{"api-keys"},
+		Responses: map[int]any{204: nil, 400: failure, 401: failure, 403: failure, 404: failure},
+		Security:  []string{openapi.SecurityBearer},
+	})
+}
+
+func (handler *ApiKeyHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		body, err := request.HandleBody[CreateApiKeyRequest](&w, r)
+		if err != nil {
+			return
+		}
+		role, _ := middleware.RoleFromContext(r.Context())
+		created, key, err := handler.ApiKeyService.Create(userId, role, body.Name, body.Scopes)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{
+			Action:   audit.ActionApiKeyCreate,
+			TargetId: created.ID,
+			Details:  created.Prefix,
+		})
+		response.Json(w, CreateApiKeyResponse{
+			ApiKeyResponse: NewApiKeyResponse(created),
+			Key:            key,
+		}, http.StatusCreated)
+	}
+}
+
+func (handler *ApiKeyHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		keys, err := handler.ApiKeyService.List(userId)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		data := ListApiKeysResponse{
+			Items: make([]ApiKeyResponse, 0, len(keys)),
+		}
+		for i := range keys {
+			data.Items = append(data.Items, NewApiKeyResponse(&keys[i]))
+		}
+		response.Json(w, data, http.StatusOK)
+	}
+}
+
+func (handler *ApiKeyHandler) Revoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(w, "invalid api key id")
+			return
+		}
+		revoked, err := handler.ApiKeyService.Revoke(uint(id), userId)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{
+			Action:   audit.ActionTokenRevoke,
+			TargetId: revoked.ID,
+			Details:  "api key " + revoked.Prefix,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		response.NotFound(w, err.Error())
+	case errors.Is(err, ErrScopeNotAllowed):
+		response.Forbidden(w, err.Error())
+	case errors.Is(err, ErrTooManyKeys):
+		response.Conflict(w, err.Error())
+	default:
+		response.InternalServerError(w, err)
+	}
+}
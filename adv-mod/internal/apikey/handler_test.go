@@ -0,0 +1,259 @@
+package apikey_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/apikey"
+	"adv-mod/internal/dbtest"
+	"adv-mod/internal/user"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/rbac"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSecret = "secret"
+
+type testEnv struct {
+	service *apikey.ApiKeyService
+	users   *user.UserRepository
+	keys    *apikey.ApiKeyRepository
+	handler http.Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	database := dbtest.Open(t, &user.User{}, &apikey.ApiKey{})
+	env := &testEnv{
+		users: user.NewUserRepository(database),
+		keys:  apikey.NewApiKeyRepository(database),
+	}
+	env.service = apikey.NewApiKeyService(apikey.ApiKeyServiceDeps{
+		ApiKeyRepository: env.keys,
+		UserRepository:   env.users,
+	})
+	router := http.NewServeMux()
+	apikey.NewHelloHandler(router, apikey.ApiKeyHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+		},
+		ApiKeyService: env.service,
+	})
+	env.handler = middleware.APIKey(env.service.Authenticate)(router)
+	return env
+}
+
+func (env *testEnv) createUser(t *testing.T, email, role string) *user.User {
+	t.Helper()
+	u, err := env.users.Create(&user.User{Email: email, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// serve с nil пользователем отправляет запрос без токена
+func (env *testEnv) serve(method, path string, u *user.User, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	if u != nil {
+		token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: u.Email, UserId: u.ID, Role: u.Role})
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreate(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser(t, "a@a.ru", rbac.RoleUser)
+	testCases := []struct {
+		name    string
+		user    *user.User
+		payload map[string]any
+		status  int
+	}{
+		{name: "Success", user: owner, payload: map[string]any{"name": "ci", "scopes": []string{rbac.PermissionStatRead, rbac.PermissionLinksRead}}, status: http.StatusCreated},
+		{name: "Scope above role", user: owner, payload: map[string]any{"name": "ci", "scopes": []string{rbac.PermissionAuditRead}}, status: http.StatusForbidden},
+		{name: "Unknown scope", user: owner, payload: map[string]any{"name": "ci", "scopes": []string{"links:delete"}}, status: http.StatusForbidden},
+		{name: "No scopes", user: owner, payload: map[string]any{"name": "ci", "scopes": []string{}}, status: http.StatusUnprocessableEntity},
+		{name: "No name", user: owner, payload: map[string]any{"scopes": []string{rbac.PermissionLinksRead}}, status: http.StatusUnprocessableEntity},
+		{name: "No token", payload: map[string]any{"name": "ci", "scopes": []string{rbac.PermissionLinksRead}}, status: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := env.serve(http.MethodPost, "/api-keys", tc.user, tc.payload)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var created apikey.CreateApiKeyResponse
+			json.NewDecoder(w.Body).Decode(&created)
+			if created.Key == "" || created.Prefix != created.Key[:apikey.PrefixLength] {
+				t.Errorf("Expected key with visible prefix, got %+v", created)
+			}
+			if !slices.Equal(created.Scopes, []string{rbac.PermissionLinksRead, rbac.PermissionStatRead}) {
+				t.Errorf("Expected sorted scopes, got %v", created.Scopes)
+			}
+			stored, err := env.keys.FindOwned(created.Id, owner.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.KeyHash == created.Key {
+				t.Error("Expected only a hash of the key to be stored")
+			}
+		})
+	}
+}
+
+func TestListAndRevoke(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser(t, "a@a.ru", rbac.RoleUser)
+	other := env.createUser(t, "b@b.ru", rbac.RoleUser)
+	own, _, err := env.service.Create(owner.ID, owner.Role, "own", []string{rbac.PermissionLinksRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _, err := env.service.Create(other.ID, other.Role, "foreign", []string{rbac.PermissionLinksRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := env.serve(http.MethodGet, "/api-keys", owner, nil)
+	var list apikey.ListApiKeysResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Items) != 1 || list.Items[0].Id != own.ID {
+		t.Fatalf("Expected only own key, got %+v", list.Items)
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "Foreign key", path: "/api-keys/" + strconv.Itoa(int(foreign.ID)), status: http.StatusNotFound},
+		{name: "Invalid id", path: "/api-keys/abc", status: http.StatusBadRequest},
+		{name: "Own key", path: "/api-keys/" + strconv.Itoa(int(own.ID)), status: http.StatusNoContent},
+		{name: "Already revoked", path: "/api-keys/" + strconv.Itoa(int(own.ID)), status: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := env.serve(http.MethodDelete, tc.path, owner, nil)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+	keys, err := env.service.List(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected revoked key to disappear from the list, got %d keys", len(keys))
+	}
+}
+
+func TestKeyCanNotManageKeys(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser(t, "a@a.ru", rbac.RoleUser)
+	_, key, err := env.service.Create(owner.ID, owner.Role, "ci", []string{rbac.PermissionLinksRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api-keys", nil)
+	req.Header.Set(middleware.APIKeyHeader, key)
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestTooManyKeys(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser(t, "a@a.ru", rbac.RoleUser)
+	for range apikey.MaxKeysPerUser {
+		if _, _, err := env.service.Create(owner.ID, owner.Role, "ci", []string{rbac.PermissionLinksRead}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := env.serve(http.MethodPost, "/api-keys", owner, map[string]any{"name": "ci", "scopes": []string{rbac.PermissionLinksRead}})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser(t, "admin@a.ru", rbac.RoleAdmin)
+	created, key, err := env.service.Create(admin.ID, admin.Role, "ci", []string{rbac.PermissionLinksRead, rbac.PermissionUsersRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := env.service.Authenticate(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserId != admin.ID || identity.Email != admin.Email || identity.Role != rbac.RoleAdmin {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+	stored, _ := env.keys.FindOwned(created.ID, admin.ID)
+	if stored.LastUsedAt == nil {
+		t.Fatal("Expected last used time to be recorded")
+	}
+	firstUse := *stored.LastUsedAt
+
+	// Повторное использование в пределах LastUsedInterval не пишет в БД
+	if _, err := env.service.Authenticate(key); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = env.keys.FindOwned(created.ID, admin.ID)
+	if !stored.LastUsedAt.Equal(firstUse) {
+		t.Errorf("Expected last used time %v, got %v", firstUse, stored.LastUsedAt)
+	}
+	if err := env.keys.TouchLastUsed(created.ID, time.Now().Add(-2*apikey.LastUsedInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.service.Authenticate(key); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = env.keys.FindOwned(created.ID, admin.ID)
+	if time.Since(*stored.LastUsedAt) > apikey.LastUsedInterval {
+		t.Errorf("Expected stale last used time to be updated, got %v", stored.LastUsedAt)
+	}
+
+	// После понижения роли scopes сверх её прав не действуют
+	if err := env.users.UpdateRole(admin.ID, rbac.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	identity, err = env.service.Authenticate(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(identity.Scopes, []string{rbac.PermissionLinksRead}) {
+		t.Errorf("Expected scopes to be limited by the role, got %v", identity.Scopes)
+	}
+
+	if err := env.users.Delete(admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{key, "ak_unknown", "not-a-key"} {
+		if _, err := env.service.Authenticate(invalid); !errors.Is(err, middleware.ErrInvalidAPIKey) {
+			t.Errorf("Expected %q to be invalid, got %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package apikey
+
+import (
+	"adv-mod/pkg/token"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// KeyPrefix отличает API ключи от других токенов, например в логах и сканерах секретов
+	KeyPrefix = "ak_"
+	// PrefixLength - сколько первых символов ключа хранится открыто, чтобы его можно было узнать в списке
+	PrefixLength = len(KeyPrefix) + 8
+)
+
+// ApiKey - ключ для доступа без входа по паролю. Сам ключ не хранится, только его хэш.
+// Отозванный ключ мягко удаляется
+type ApiKey struct {
+	gorm.Model
+	UserId     uint     `gorm:"index;not null"`
+	Name       string   `gorm:"not null"`
+	Prefix     string   `gorm:"not null"`
+	KeyHash    string   `gorm:"uniqueIndex;not null"`
+	Scopes     []string `gorm:"serializer:json;not null"`
+	LastUsedAt *time.Time
+}
+
+// GenerateKey возвращает новый ключ и его видимый префикс
+func GenerateKey() (key, prefix string, err error) {
+	raw, err := token.Generate()
+	if err != nil {
+		return "", "", err
+	}
+	key = KeyPrefix + raw
+	return key, key[:PrefixLength], nil
+}
@@ -0,0 +1,38 @@
+package apikey
+
+import "time"
+
+type CreateApiKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+type ApiKeyResponse struct {
+	Id         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateApiKeyResponse - единственный ответ, в котором есть сам ключ
+type CreateApiKeyResponse struct {
+	ApiKeyResponse
+	Key string `json:"key"`
+}
+
+type ListApiKeysResponse struct {
+	Items []ApiKeyResponse `json:"items"`
+}
+
+func NewApiKeyResponse(key *ApiKey) ApiKeyResponse {
+	return ApiKeyResponse{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package apikey
+
+import (
+	"adv-mod/pkg/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ApiKeyRepository struct {
+	Database *db.Db
+}
+
+func NewApiKeyRepository(database *db.Db) *ApiKeyRepository {
+	return &ApiKeyRepository{
+		Database: database,
+	}
+}
+
+func (repo *ApiKeyRepository) Create(key *ApiKey) (*ApiKey, error) {
+	result := repo.Database.DB.Create(key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return key, nil
+}
+
+// ListByUser возвращает действующие ключи пользователя, новые первыми
+func (repo *ApiKeyRepository) ListByUser(userId uint) ([]ApiKey, error) {
+	var keys []ApiKey
+	result := repo.Database.DB.Where("user_id = ?", userId).Order("id DESC").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+func (repo *ApiKeyRepository) CountByUser(userId uint) (int64, error) {
+	var count int64
+	result := repo.Database.DB.Model(&ApiKey{}).Where("user_id = ?", userId).Count(&count)
+	return count, result.Error
+}
+
+func (repo *ApiKeyRepository) FindByHash(hash string) (*ApiKey, error) {
+	var key ApiKey
+	result := repo.Database.DB.First(&key, "key_hash = ?", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// FindOwned находит ключ пользователя. Чужой ключ неотличим от отсутствующего
+func (repo *ApiKeyRepository) FindOwned(id, userId uint) (*ApiKey, error) {
+	var key ApiKey
+	result := repo.Database.DB.First(&key, "id = ? AND user_id = ?", id, userId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// Revoke мягко удаляет ключ: он пропадает из списка и перестаёт проходить проверку
+func (repo *ApiKeyRepository) Revoke(id uint) error {
+	result := repo.Database.DB.Delete(&ApiKey{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed обновляет время использования без UpdatedAt: это не изменение ключа
+func (repo *ApiKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	result := repo.Database.DB.Model(&ApiKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", at)
+	return result.Error
+}
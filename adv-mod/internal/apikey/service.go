@@ -0,0 +1,140 @@
+package apikey
+
+import (
+	"adv-mod/pkg/di"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/token"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxKeysPerUser ограничивает число действующих ключей одного пользователя
+	MaxKeysPerUser = 20
+	// LastUsedInterval - не чаще этого время использования пишется в БД,
+	// чтобы каждый запрос с ключом не был записью
+	LastUsedInterval = time.Minute
+)
+
+type ApiKeyServiceDeps struct {
+	ApiKeyRepository *ApiKeyRepository
+	UserRepository   di.IUserRepository
+}
+
+type ApiKeyService struct {
+	ApiKeyRepository *ApiKeyRepository
+	UserRepository   di.IUserRepository
+}
+
+func NewApiKeyService(deps ApiKeyServiceDeps) *ApiKeyService {
+	return &ApiKeyService{
+		ApiKeyRepository: deps.ApiKeyRepository,
+		UserRepository:   deps.UserRepository,
+	}
+}
+
+// Create выпускает ключ. Ключ в открытом виде возвращается только здесь.
+// Scopes не могут превышать права роли владельца
+func (service *ApiKeyService) Create(userId uint, role, name string, scopes []string) (*ApiKey, string, error) {
+	for _, scope := range scopes {
+		if !rbac.HasPermission(role, scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+	count, err := service.ApiKeyRepository.CountByUser(userId)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= MaxKeysPerUser {
+		return nil, "", ErrTooManyKeys
+	}
+	key, prefix, err := GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	slices.Sort(scopes)
+	created, err := service.ApiKeyRepository.Create(&ApiKey{
+		UserId:  userId,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: token.Hash(key),
+		Scopes:  slices.Compact(scopes),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return created, key, nil
+}
+
+func (service *ApiKeyService) List(userId uint) ([]ApiKey, error) {
+	return service.ApiKeyRepository.ListByUser(userId)
+}
+
+// Revoke отзывает ключ пользователя и возвращает его для журнала аудита
+func (service *ApiKeyService) Revoke(id, userId uint) (*ApiKey, error) {
+	key, err := service.ApiKeyRepository.FindOwned(id, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = service.ApiKeyRepository.Revoke(key.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Authenticate - middleware.APIKeyLookup. Роль берётся у владельца на момент запроса,
+// а scopes, которые роль больше не даёт, отбрасываются
+func (service *ApiKeyService) Authenticate(key string) (*middleware.APIKeyIdentity, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	stored, err := service.ApiKeyRepository.FindByHash(token.Hash(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Ключи удалённого пользователя перестают работать вместе с ним
+	owner, err := service.UserRepository.FindById(stored.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= LastUsedInterval {
+		err = service.ApiKeyRepository.TouchLastUsed(stored.ID, now)
+		if err != nil {
+			// Запрос важнее отметки об использовании
+			slog.Warn("api key last used update failed", "key", stored.Prefix, "error", err)
+		}
+	}
+	scopes := make([]string, 0, len(stored.Scopes))
+	for _, scope := range stored.Scopes {
+		if rbac.HasPermission(owner.Role, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &middleware.APIKeyIdentity{
+		UserId: owner.ID,
+		Email:  owner.Email,
+		Role:   owner.Role,
+		Scopes: scopes,
+	}, nil
+}
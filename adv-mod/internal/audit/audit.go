@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"adv-mod/pkg/metrics"
+	"adv-mod/pkg/middleware"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxUserAgentLength ограничивает заголовок, который присылает клиент
+const maxUserAgentLength = 512
+
+// Sink сохраняет записи аудита
+type Sink interface {
+	Write(entry *Entry) error
+}
+
+// Recorder пишет записи в sink синхронно, в рамках запроса: очередь, которая
+// при переполнении или остановке теряет записи, журналу аудита не подходит
+type Recorder struct {
+	Sink     Sink
+	Failures *metrics.Counter
+}
+
+// NewRecorder создаёт Recorder. С registry ошибки записи считаются в метрике
+// audit_write_failures_total
+func NewRecorder(sink Sink, registry *metrics.Registry) *Recorder {
+	recorder := &Recorder{Sink: sink}
+	if registry != nil {
+		recorder.Failures = registry.NewCounter("audit_write_failures_total", "Audit entries that could not be written.", "action")
+	}
+	return recorder
+}
+
+// Record дополняет запись данными запроса и сохраняет её. Актор берётся
+// из access токена, если его не указали явно, например при входе. Ошибка записи
+// не должна ронять запрос, который уже выполнен, поэтому она логируется и
+// считается. На nil ничего не делает
+func (recorder *Recorder) Record(r *http.Request, entry Entry) {
+	if recorder == nil {
+		return
+	}
+	ctx := r.Context()
+	if entry.ActorId == 0 {
+		entry.ActorId, _ = middleware.UserIdFromContext(ctx)
+	}
+	if entry.ActorEmail == "" {
+		entry.ActorEmail, _ = middleware.EmailFromContext(ctx)
+	}
+	entry.CreatedAt = time.Now()
+	entry.Ip = middleware.ClientIP(r)
+	entry.UserAgent = r.UserAgent()
+	if len(entry.UserAgent) > maxUserAgentLength {
+		entry.UserAgent = entry.UserAgent[:maxUserAgentLength]
+	}
+	entry.RequestId, _ = middleware.RequestIDFromContext(ctx)
+	err := recorder.Sink.Write(&entry)
+	if err != nil {
+		slog.Error("write audit entry", "error", err, "action", entry.Action, "request_id", entry.RequestId)
+		if recorder.Failures != nil {
+			recorder.Failures.Inc(entry.Action)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package audit_test
+
+import (
+	"adv-mod/internal/audit"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/metrics"
+	"adv-mod/pkg/middleware"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (sink *memorySink) Write(entry *audit.Entry) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.entries = append(sink.entries, *entry)
+	return nil
+}
+
+func TestRecordAddsRequestData(t *testing.T) {
+	sink := &memorySink{}
+	recorder := audit.NewRecorder(sink, nil)
+
+	handler := middleware.RequestID(middleware.IsAuthed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder.Record(r, audit.Entry{Action: audit.ActionRoleChange, TargetId: 2})
+	}), newTestConfig()))
+	req := httptest.NewRequest(http.MethodPatch, "/admin/users/2/role", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("User-Agent", "curl/8.0 "+strings.Repeat("x", 1000))
+	req.Header.Set("X-Request-ID", "req-1")
+	token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "admin@a.ru", UserId: 1})
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(sink.entries) != 1 {
+		t.Fatalf("Expected one entry, got %d", len(sink.entries))
+	}
+	entry := sink.entries[0]
+	if entry.ActorId != 1 || entry.ActorEmail != "admin@a.ru" || entry.TargetId != 2 {
+		t.Errorf("Unexpected actor or target %+v", entry)
+	}
+	if entry.Ip != "10.0.0.1" || entry.RequestId != "req-1" || entry.CreatedAt.IsZero() {
+		t.Errorf("Unexpected request data %+v", entry)
+	}
+	if !strings.HasPrefix(entry.UserAgent, "curl/8.0") || len(entry.UserAgent) > 512 {
+		t.Errorf("Expected truncated user agent, got %d bytes", len(entry.UserAgent))
+	}
+}
+
+func TestRecordKeepsExplicitActor(t *testing.T) {
+	sink := &memorySink{}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	audit.NewRecorder(sink, nil).Record(req, audit.Entry{Action: audit.ActionLoginFailure, ActorEmail: "a@a.ru"})
+
+	if len(sink.entries) != 1 || sink.entries[0].ActorEmail != "a@a.ru" || sink.entries[0].ActorId != 0 {
+		t.Errorf("Unexpected entries %+v", sink.entries)
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Write(entry *audit.Entry) error {
+	return errors.New("disk full")
+}
+
+func TestRecordCountsFailures(t *testing.T) {
+	registry := metrics.NewRegistry()
+	recorder := audit.NewRecorder(failingSink{}, registry)
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	recorder.Record(req, audit.Entry{Action: audit.ActionLoginFailure})
+	recorder.Record(req, audit.Entry{Action: audit.ActionLoginFailure})
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+	line := `audit_write_failures_total{action="login.failure"} 2`
+	if !strings.Contains(out.String(), line) {
+		t.Errorf("Expected %q in:\n%s", line, out.String())
+	}
+}
+
+func TestRecordNil(t *testing.T) {
+	var recorder *audit.Recorder
+	recorder.Record(httptest.NewRequest(http.MethodGet, "/", nil), audit.Entry{Action: audit.ActionRegister})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := audit.NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []string{audit.ActionLoginSuccess, audit.ActionTokenRevoke}
+	for _, action := range actions {
+		if err := sink.Write(&audit.Entry{Action: action, Ip: "10.0.0.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Повторное открытие дописывает, а не перезаписывает
+	sink, err = audit.NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write(&audit.Entry{Action: audit.ActionRegister})
+	sink.Close()
+	actions = append(actions, audit.ActionRegister)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var lines int
+	for scanner.Scan() {
+		var entry audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Line %d is not JSON: %v", lines+1, err)
+		}
+		if entry.Action != actions[lines] {
+			t.Errorf("Expected %s on line %d, got %s", actions[lines], lines+1, entry.Action)
+		}
+		lines++
+	}
+	if lines != len(actions) {
+		t.Errorf("Expected %d lines, got %d", len(actions), lines)
+	}
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/db"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/response"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type AuditHandlerDeps struct {
+	*configs.Config
+	AuditRepository *AuditRepository
+	OpenAPI         *openapi.Registry
+}
+
+type AuditHandler struct {
+	*configs.Config
+	AuditRepository *AuditRepository
+}
+
+// NewHelloHandler регистрирует просмотр журнала аудита для администраторов
+func NewHelloHandler(router *http.ServeMux, deps AuditHandlerDeps) {
+	handler := &AuditHandler{
+		Config:          deps.Config,
+		AuditRepository: deps.AuditRepository,
+	}
+	canRead := middleware.RequirePermission(rbac.PermissionAuditRead)
+	failure := response.ErrorBody{}
+	deps.OpenAPI.Handle(router, "GET /audit", middleware.IsAuthed(canRead(handler.List()), deps.Config), openapi.Operation{
+		Summary:     "Search the audit log",
+		Description: "Filters support operators, e.g. created_at[gte]=2026-10-01T00:00:00Z. Newest entries first by default.",
+		Tags:        []string{"admin"},
+		Query: []openapi.Parameter{
+			{Name: "limit", Type: "integer", Description: "page size, up to " + strconv.Itoa(db.MaxLimit)},
+			{Name: "offset", Type: "integer"},
+			{Name: "cursor", Description: "next_cursor of the previous page"},
+			{Name: "sort", Description: "id or created_at; prefix - for descending order"},
+			{Name: "action", Description: "e.g. " + ActionLoginFailure},
+			{Name: "actor_id", Type: "integer"},
+			{Name: "actor_email"},
+			{Name: "target_id", Type: "integer"},
+			{Name: "ip"},
+			{Name: "request_id"},
+			{Name: "created_at", Description: "RFC 3339 time, use with [gte] and [lte]"},
+		},
+		Responses: map[int]any{200: ListEntriesResponse{}, 400: failure, 401: failure, 403: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+}
+
+func (handler *AuditHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := handler.AuditRepository.ParseQuery(r.URL.Query())
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		page, err := handler.AuditRepository.List(query)
+		if errors.Is(err, db.ErrInvalidQuery) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		response.Json(w, ListEntriesResponse{
+			Items:      page.Items,
+			Total:      page.Total,
+			NextCursor: page.NextCursor,
+			Limit:      page.Limit,
+			Offset:     page.Offset,
+		}, http.StatusOK)
+	}
+}
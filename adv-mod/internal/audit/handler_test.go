@@ -0,0 +1,122 @@
+package audit_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/audit"
+	"adv-mod/internal/dbtest"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/rbac"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testSecret = "secret"
+
+func newTestConfig() *configs.Config {
+	return &configs.Config{
+		Auth: configs.AuthConfig{Secret: testSecret},
+	}
+}
+
+func newTestRepository(t *testing.T) *audit.AuditRepository {
+	t.Helper()
+	return audit.NewAuditRepository(dbtest.Open(t, &audit.Entry{}))
+}
+
+func TestListAudit(t *testing.T) {
+	repo := newTestRepository(t)
+	start := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{Action: audit.ActionLoginFailure, ActorEmail: "a@a.ru", Ip: "10.0.0.1"},
+		{Action: audit.ActionLoginFailure, ActorEmail: "a@a.ru", Ip: "10.0.0.2"},
+		{Action: audit.ActionLoginSuccess, ActorEmail: "a@a.ru", Ip: "10.0.0.1"},
+		{Action: audit.ActionRoleChange, ActorId: 1, ActorEmail: "admin@a.ru", TargetId: 2},
+	}
+	for i := range entries {
+		entries[i].CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		if err := repo.Write(&entries[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := http.NewServeMux()
+	audit.NewHelloHandler(router, audit.AuditHandlerDeps{
+		Config:          newTestConfig(),
+		AuditRepository: repo,
+	})
+
+	testCases := []struct {
+		name    string
+		role    string
+		query   string
+		status  int
+		actions []string
+		total   int64
+	}{
+		{
+			name:    "Newest first",
+			role:    rbac.RoleAdmin,
+			status:  http.StatusOK,
+			actions: []string{audit.ActionRoleChange, audit.ActionLoginSuccess, audit.ActionLoginFailure, audit.ActionLoginFailure},
+			total:   4,
+		},
+		{
+			name:    "Filter by action and ip",
+			role:    rbac.RoleAdmin,
+			query:   "action=login.failure&ip=10.0.0.2",
+			status:  http.StatusOK,
+			actions: []string{audit.ActionLoginFailure},
+			total:   1,
+		},
+		{
+			name:    "Filter by time",
+			role:    rbac.RoleAdmin,
+			query:   "created_at[gte]=2026-10-18T11:00:00Z&created_at[lte]=2026-10-18T12:00:00Z",
+			status:  http.StatusOK,
+			actions: []string{audit.ActionLoginSuccess, audit.ActionLoginFailure},
+			total:   2,
+		},
+		{
+			name:    "Pagination",
+			role:    rbac.RoleAdmin,
+			query:   "limit=1&offset=1",
+			status:  http.StatusOK,
+			actions: []string{audit.ActionLoginSuccess},
+			total:   4,
+		},
+		{name: "Unknown sort field", role: rbac.RoleAdmin, query: "sort=user_agent", status: http.StatusBadRequest},
+		{name: "Regular user", role: rbac.RoleUser, status: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/audit?"+tc.query, nil)
+			token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "admin@a.ru", UserId: 1, Role: tc.role})
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			var resp audit.ListEntriesResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Total == nil || *resp.Total != tc.total {
+				t.Errorf("Expected total %d, got %v", tc.total, resp.Total)
+			}
+			actions := make([]string, 0, len(resp.Items))
+			for _, entry := range resp.Items {
+				actions = append(actions, entry.Action)
+			}
+			if !slices.Equal(actions, tc.actions) {
+				t.Errorf("Expected %v, got %v", tc.actions, actions)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package audit
+
+import "time"
+
+const (
+	ActionLoginSuccess   = "login.success"
+	ActionLoginFailure   = "login.failure"
+	ActionRegister       = "user.register"
+	ActionUserDelete     = "user.delete"
+	ActionPasswordChange = "password.change"
+	ActionPasswordReset  = "password.reset"
+	ActionRoleChange     = "role.change"
+	ActionTokenRevoke    = "token.revoke"
+	ActionApiKeyCreate   = "apikey.create"
+)
+
+// Entry - запись журнала аудита. Журнал только пополняется, поэтому у модели
+// нет UpdatedAt и DeletedAt. Json теги задают формат файлового журнала и API
+type Entry struct {
+	ID         uint      `gorm:"primarykey" json:"id,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	Action     string    `gorm:"index;not null" json:"action"`
+	ActorId    uint      `gorm:"index" json:"actor_id,omitempty"`
+	ActorEmail string    `gorm:"index" json:"actor_email,omitempty"`
+	TargetId   uint      `json:"target_id,omitempty"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	RequestId  string    `gorm:"index" json:"request_id"`
+	Details    string    `json:"details,omitempty"`
+}
+
+func (Entry) TableName() string {
+	return "audit_entries"
+}
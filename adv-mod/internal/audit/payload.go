@@ -0,0 +1,9 @@
+package audit
+
+type ListEntriesResponse struct {
+	Items      []Entry `json:"items"`
+	Total      *int64  `json:"total,omitempty"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	Limit      int     `json:"limit"`
+	Offset     int     `json:"offset,omitempty"`
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"adv-mod/pkg/db"
+	"net/url"
+)
+
+// AuditRepository - хранилище журнала в БД. Изменения и удаление записей
+// не предусмотрены, в Postgres их дополнительно запрещает триггер
+type AuditRepository struct {
+	Database *db.Db
+	entries  *db.Repository[Entry]
+}
+
+func NewAuditRepository(database *db.Db) *AuditRepository {
+	return &AuditRepository{
+		Database: database,
+		entries: db.NewRepository[Entry](database, db.ListOptions{
+			Filters: map[string]string{
+				"action":      "action",
+				"actor_id":    "actor_id",
+				"actor_email": "actor_email",
+				"target_id":   "target_id",
+				"ip":          "ip",
+				"request_id":  "request_id",
+				"created_at":  "created_at",
+			},
+			Sorts: map[string]string{
+				"id":         "id",
+				"created_at": "created_at",
+			},
+			DefaultSort: "-id",
+		}),
+	}
+}
+
+// Write реализует Sink
+func (repo *AuditRepository) Write(entry *Entry) error {
+	_, err := repo.entries.Create(entry)
+	return err
+}
+
+func (repo *AuditRepository) ParseQuery(values url.Values) (db.ListQuery, error) {
+	return repo.entries.ParseQuery(values)
+}
+
+func (repo *AuditRepository) List(query db.ListQuery) (*db.Page[Entry], error) {
+	return repo.entries.List(query)
+}
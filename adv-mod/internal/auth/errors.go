@@ -0,0 +1,13 @@
+package auth
+
+import "errors"
+
+var (
+	ErrUserExists          = errors.New("user already exists")
+	ErrWrongCredentials    = errors.New("wrong email or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+
+	ErrInvalidVerificationToken = errors.New("invalid or expired token")
+)
@@ -2,10 +2,15 @@ package auth
 
 import (
 	"adv-mod/configs"
+	"adv-mod/internal/audit"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/ratelimit"
 	"adv-mod/pkg/request"
 	"adv-mod/pkg/response"
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
 )
 
 // func hello(w http.ResponseWriter, req *http.Request) {
@@ -15,19 +20,89 @@ import (
 
 type AuthHandlerDeps struct {
 	*configs.Config
+	*AuthService
+	RateLimitStore ratelimit.Store
+	Audit          *audit.Recorder
+	OpenAPI        *openapi.Registry
 }
 
 type AuthHandler struct {
 	*configs.Config
+	*AuthService
+	RateLimitStore ratelimit.Store
+	Audit          *audit.Recorder
 }
 
 func NewHelloHandler(router *http.ServeMux, deps AuthHandlerDeps) {
 	handler := &AuthHandler{
-		Config: deps.Config,
+		Config:         deps.Config,
+		AuthService:    deps.AuthService,
+		RateLimitStore: deps.RateLimitStore,
+		Audit:          deps.Audit,
 	}
-	router.HandleFunc("POST /auth/login", handler.Login())
-	router.HandleFunc("POST /auth/register", handler.Register())
-
+	ipLimit := middleware.RateLimit(deps.RateLimitStore, ratelimit.Limit{
+		Requests: deps.Config.RateLimit.AuthIpRequests,
+		Per:      deps.Config.RateLimit.AuthIpPer,
+	}, middleware.ByClientIP("auth"))
+	api := deps.OpenAPI
+	failure := response.ErrorBody{}
+	api.Handle(router, "POST /auth/login", ipLimit(handler.Login()), openapi.Operation{
+		Summary:   "Log in with email and password",
+		Tags:      []string{"auth"},
+		Request:   LoginRequest{},
+		Responses: map[int]any{200: LoginResponse{}, 401: failure, 422: failure, 429: failure},
+	})
+	api.Handle(router, "POST /auth/register", ipLimit(handler.Register()), openapi.Operation{
+		Summary:   "Create an account and send a verification email",
+		Tags:      []string{"auth"},
+		Request:   RegisterRequest{},
+		Responses: map[int]any{201: RegisterResponse{}, 409: failure, 422: failure, 429: failure},
+	})
+	api.Handle(router, "POST /auth/refresh", ipLimit(handler.Refresh()), openapi.Operation{
+		Summary:   "Rotate a refresh token",
+		Tags:      []string{"auth"},
+		Request:   RefreshRequest{},
+		Responses: map[int]any{200: RefreshResponse{}, 401: failure, 422: failure, 429: failure},
+	})
+	api.Handle(router, "POST /auth/logout", handler.Logout(), openapi.Operation{
+		Summary:   "Revoke the session of a refresh token",
+		Tags:      []string{"auth"},
+		Request:   LogoutRequest{},
+		Responses: map[int]any{204: nil, 422: failure},
+	})
+	api.Handle(router, "POST /auth/verify-email", handler.VerifyEmail(), openapi.Operation{
+		Summary:   "Confirm an email address",
+		Tags:      []string{"auth"},
+		Request:   VerifyEmailRequest{},
+		Responses: map[int]any{204: nil, 400: failure, 422: failure},
+	})
+	api.Handle(router, "GET /auth/verify-email", handler.VerifyEmailLink(), openapi.Operation{
+		Summary:     "Confirm an email address from the link in the email",
+		Description: "Renders an HTML page with the result.",
+		Tags:        []string{"auth"},
+		Query:       []openapi.Parameter{{Name: "token", Required: true}},
+		Responses:   map[int]any{200: nil, 400: nil},
+	})
+	api.Handle(router, "POST /auth/forgot-password", ipLimit(handler.ForgotPassword()), openapi.Operation{
+		Summary:   "Send a password reset email",
+		Tags:      []string{"auth"},
+		Request:   ForgotPasswordRequest{},
+		Responses: map[int]any{202: nil, 422: failure, 429: failure},
+	})
+	api.Handle(router, "POST /auth/reset-password", handler.ResetPassword(), openapi.Operation{
+		Summary:     "Set a new password with a reset token",
+		Tags:        []string{"auth"},
+		Request:     ResetPasswordRequest{},
+		Description: "A form submission gets an HTML page instead of 204.",
+		Responses:   map[int]any{200: nil, 204: nil, 400: failure, 422: failure},
+	})
+	api.Handle(router, "GET /auth/reset-password", handler.ResetPasswordForm(), openapi.Operation{
+		Summary:     "Form for a new password, opened from the link in the email",
+		Description: "Renders an HTML form that submits to POST /auth/reset-password.",
+		Tags:        []string{"auth"},
+		Query:       []openapi.Parameter{{Name: "token", Required: true}},
+		Responses:   map[int]any{200: nil, 400: nil},
+	})
 }
 
 func (handler *AuthHandler) Login() http.HandlerFunc {
@@ -36,21 +111,221 @@ func (handler *AuthHandler) Login() http.HandlerFunc {
 		if err != nil {
 			return
 		}
-		fmt.Println(body)
+		emailLimit := ratelimit.Limit{
+			Requests: handler.Config.RateLimit.LoginEmailRequests,
+			Per:      handler.Config.RateLimit.LoginEmailPer,
+		}
+		if !emailLimit.Disabled() {
+			result, err := handler.RateLimitStore.Take("login:email:"+strings.ToLower(body.Email), emailLimit)
+			if err != nil {
+				response.InternalServerError(w, err)
+				return
+			}
+			if !result.Allowed {
+				response.TooManyRequests(w, result.RetryAfter, "too many login attempts for this account")
+				return
+			}
+		}
+		tokens, err := handler.AuthService.Login(body.Email, body.Password)
+		var lockedError *ratelimit.LockedError
+		if errors.As(err, &lockedError) {
+			handler.Audit.Record(r, audit.Entry{Action: audit.ActionLoginFailure, ActorEmail: body.Email, Details: "account locked"})
+			response.TooManyRequests(w, lockedError.RetryAfter, lockedError.Error())
+			return
+		}
+		if errors.Is(err, ErrWrongCredentials) {
+			handler.Audit.Record(r, audit.Entry{Action: audit.ActionLoginFailure, ActorEmail: body.Email, Details: err.Error()})
+			response.Unauthorized(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{Action: audit.ActionLoginSuccess, ActorEmail: body.Email})
 		data := LoginResponse{
-			Token: "123",
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
 		}
-		response.Json(w, data, 200)
+		response.Json(w, data, http.StatusOK)
 
 	}
 }
 
-func (Handler *AuthHandler) Register() http.HandlerFunc {
+func (handler *AuthHandler) Register() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := request.HandleBody[RegisterRequest](&w, r)
 		if err != nil {
 			return
 		}
-		fmt.Println(body)
+		tokens, err := handler.AuthService.Register(body.Email, body.Password, body.Name)
+		if errors.Is(err, ErrUserExists) {
+			response.Conflict(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{Action: audit.ActionRegister, ActorEmail: body.Email})
+		data := RegisterResponse{
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		}
+		response.Json(w, data, http.StatusCreated)
+	}
+}
+
+func (handler *AuthHandler) Refresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := request.HandleBody[RefreshRequest](&w, r)
+		if err != nil {
+			return
+		}
+		tokens, revoked, err := handler.AuthService.Refresh(body.RefreshToken)
+		if revoked != nil {
+			// Токен предъявил кто угодно, поэтому владелец сессии - цель, а не актор
+			handler.Audit.Record(r, audit.Entry{
+				Action:   audit.ActionTokenRevoke,
+				TargetId: revoked.UserId,
+				Details:  err.Error() + ", family " + revoked.FamilyId,
+			})
+		}
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenExpired) || errors.Is(err, ErrRefreshTokenReused) {
+			response.Unauthorized(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		data := RefreshResponse{
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		}
+		response.Json(w, data, http.StatusOK)
+	}
+}
+
+func (handler *AuthHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := request.HandleBody[LogoutRequest](&w, r)
+		if err != nil {
+			return
+		}
+		revoked, err := handler.AuthService.Logout(body.RefreshToken)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		if revoked != nil {
+			handler.Audit.Record(r, audit.Entry{
+				Action:     audit.ActionTokenRevoke,
+				ActorId:    revoked.UserId,
+				ActorEmail: revoked.Email,
+				TargetId:   revoked.UserId,
+				Details:    "logout, family " + revoked.FamilyId,
+			})
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (handler *AuthHandler) VerifyEmail() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := request.HandleBody[VerifyEmailRequest](&w, r)
+		if err != nil {
+			return
+		}
+		err = handler.AuthService.VerifyEmail(body.Token)
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// VerifyEmailLink подтверждает email по ссылке из письма
+func (handler *AuthHandler) VerifyEmailLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := handler.AuthService.VerifyEmail(r.URL.Query().Get("token"))
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			renderMessage(w, "Ссылка недействительна", "Ссылка устарела или уже использована. Запросите письмо заново.", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		renderMessage(w, "Email подтверждён", "Спасибо! Адрес подтверждён, страницу можно закрыть.", http.StatusOK)
+	}
+}
+
+func (handler *AuthHandler) ForgotPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := request.HandleBody[ForgotPasswordRequest](&w, r)
+		if err != nil {
+			return
+		}
+		err = handler.AuthService.ForgotPassword(body.Email)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (handler *AuthHandler) ResetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := request.HandleBody[ResetPasswordRequest](&w, r)
+		if err != nil {
+			return
+		}
+		u, err := handler.AuthService.ResetPassword(body.Token, body.Password)
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			if isFormSubmit(r) {
+				renderMessage(w, "Ссылка недействительна", "Ссылка устарела или уже использована. Запросите восстановление заново.", http.StatusBadRequest)
+				return
+			}
+			response.BadRequest(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		handler.Audit.Record(r, audit.Entry{
+			Action:     audit.ActionPasswordReset,
+			ActorId:    u.ID,
+			ActorEmail: u.Email,
+			TargetId:   u.ID,
+		})
+		if isFormSubmit(r) {
+			renderMessage(w, "Пароль изменён", "Войдите с новым паролем. Все прежние сессии завершены.", http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ResetPasswordForm показывает форму нового пароля по ссылке из письма.
+// Токен проверяется только при отправке, чтобы открытие ссылки его не тратило
+func (handler *AuthHandler) ResetPasswordForm() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			renderMessage(w, "Ссылка недействительна", "В ссылке нет токена. Запросите восстановление заново.", http.StatusBadRequest)
+			return
+		}
+		renderResetForm(w, token)
 	}
 }
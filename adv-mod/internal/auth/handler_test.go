@@ -0,0 +1,396 @@
+package auth_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/audit"
+	"adv-mod/internal/auth"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/mailer"
+	"adv-mod/pkg/ratelimit"
+	"adv-mod/pkg/rbac"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRouter() *http.ServeMux {
+	router := http.NewServeMux()
+	auth.NewHelloHandler(router, auth.AuthHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+		},
+		AuthService: newTestAuthService(NewMockUserRepository()),
+	})
+	return router
+}
+
+func postJson(router http.Handler, path string, payload any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterHandler(t *testing.T) {
+	router := newTestRouter()
+	payload := auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+		Name:     "Vasya",
+	}
+
+	w := postJson(router, "/auth/register", payload)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var resp auth.RegisterResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	data, err := jwt.NewJWT(testSecret).Parse(resp.Token)
+	if err != nil {
+		t.Fatalf("Token is invalid: %v", err)
+	}
+	if data.Email != payload.Email {
+		t.Errorf("Expected email %q, got %q", payload.Email, data.Email)
+	}
+	if data.Role != rbac.RoleUser {
+		t.Errorf("Expected role %q, got %q", rbac.RoleUser, data.Role)
+	}
+
+	w = postJson(router, "/auth/register", payload)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	router := newTestRouter()
+	postJson(router, "/auth/register", auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+		Name:     "Vasya",
+	})
+
+	w := postJson(router, "/auth/login", auth.LoginRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp auth.LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.NewJWT(testSecret).Parse(resp.Token); err != nil {
+		t.Errorf("Token is invalid: %v", err)
+	}
+}
+
+func TestLoginHandlerWrongCredentials(t *testing.T) {
+	router := newTestRouter()
+	postJson(router, "/auth/register", auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+		Name:     "Vasya",
+	})
+
+	testCases := []struct {
+		name    string
+		payload auth.LoginRequest
+	}{
+		{name: "Wrong password", payload: auth.LoginRequest{Email: "a@a.ru", Password: "wrong"}},
+		{name: "Unknown email", payload: auth.LoginRequest{Email: "b@b.ru", Password: "Secret123"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := postJson(router, "/auth/login", tc.payload)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON body, got %q", ct)
+			}
+		})
+	}
+}
+
+func TestRegisterHandlerWeakPassword(t *testing.T) {
+	w := postJson(newTestRouter(), "/auth/register", auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "secret",
+		Name:     "Vasya",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestRefreshAndLogoutHandlers(t *testing.T) {
+	router := newTestRouter()
+	w := postJson(router, "/auth/register", auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+		Name:     "Vasya",
+	})
+	var registered auth.RegisterResponse
+	json.NewDecoder(w.Body).Decode(&registered)
+	if registered.RefreshToken == "" || registered.ExpiresIn <= 0 {
+		t.Fatalf("Unexpected register response %+v", registered)
+	}
+
+	w = postJson(router, "/auth/refresh", auth.RefreshRequest{RefreshToken: registered.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var refreshed auth.RefreshResponse
+	json.NewDecoder(w.Body).Decode(&refreshed)
+
+	w = postJson(router, "/auth/refresh", auth.RefreshRequest{RefreshToken: registered.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Reused token: expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	w = postJson(router, "/auth/logout", auth.LogoutRequest{RefreshToken: refreshed.RefreshToken})
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+}
+
+type memorySink struct {
+	entries []audit.Entry
+}
+
+func (sink *memorySink) Write(entry *audit.Entry) error {
+	sink.entries = append(sink.entries, *entry)
+	return nil
+}
+
+func TestSessionChangesAreAudited(t *testing.T) {
+	sink := &memorySink{}
+	authService := newTestAuthService(NewMockUserRepository())
+	router := http.NewServeMux()
+	auth.NewHelloHandler(router, auth.AuthHandlerDeps{
+		Config:      &configs.Config{Auth: configs.AuthConfig{Secret: testSecret}},
+		AuthService: authService,
+		Audit:       audit.NewRecorder(sink, nil),
+	})
+	first, err := authService.Register("a@a.ru", "Secret123", "Vasya")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := authService.Login("a@a.ru", "Secret123")
+	authService.ForgotPassword("a@a.ru")
+	resetToken := lastToken(t, authService)
+
+	testCases := []struct {
+		name    string
+		path    string
+		payload any
+		entry   *audit.Entry
+	}{
+		{name: "Logout unknown token", path: "/auth/logout", payload: auth.LogoutRequest{RefreshToken: "unknown"}},
+		{
+			name:    "Logout",
+			path:    "/auth/logout",
+			payload: auth.LogoutRequest{RefreshToken: first.RefreshToken},
+			entry:   &audit.Entry{Action: audit.ActionTokenRevoke, ActorId: 1, ActorEmail: "a@a.ru", TargetId: 1},
+		},
+		{name: "Logout again", path: "/auth/logout", payload: auth.LogoutRequest{RefreshToken: first.RefreshToken}},
+		{name: "Refresh", path: "/auth/refresh", payload: auth.RefreshRequest{RefreshToken: second.RefreshToken}},
+		{
+			name:    "Refresh reused",
+			path:    "/auth/refresh",
+			payload: auth.RefreshRequest{RefreshToken: second.RefreshToken},
+			entry:   &audit.Entry{Action: audit.ActionTokenRevoke, TargetId: 1},
+		},
+		{
+			name:    "Reset password",
+			path:    "/auth/reset-password",
+			payload: auth.ResetPasswordRequest{Token: resetToken, Password: "NewSecret456"},
+			entry:   &audit.Entry{Action: audit.ActionPasswordReset, ActorId: 1, ActorEmail: "a@a.ru", TargetId: 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sink.entries = nil
+			postJson(router, tc.path, tc.payload)
+			if tc.entry == nil {
+				if len(sink.entries) != 0 {
+					t.Errorf("Expected no audit entry, got %+v", sink.entries)
+				}
+				return
+			}
+			if len(sink.entries) != 1 {
+				t.Fatalf("Expected one audit entry, got %+v", sink.entries)
+			}
+			got := sink.entries[0]
+			if got.Action != tc.entry.Action || got.ActorId != tc.entry.ActorId || got.ActorEmail != tc.entry.ActorEmail || got.TargetId != tc.entry.TargetId {
+				t.Errorf("Expected %+v, got %+v", tc.entry, got)
+			}
+		})
+	}
+}
+
+func newRateLimitedRouter() *http.ServeMux {
+	store := ratelimit.NewMemoryStore()
+	authService := newTestAuthService(NewMockUserRepository())
+	authService.Lockout = store
+	authService.LockoutPolicy = ratelimit.LockoutPolicy{
+		MaxFailures:  3,
+		BaseDuration: time.Minute,
+		MaxDuration:  time.Hour,
+	}
+	router := http.NewServeMux()
+	auth.NewHelloHandler(router, auth.AuthHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+			RateLimit: configs.RateLimitConfig{
+				AuthIpRequests:     20,
+				AuthIpPer:          time.Minute,
+				LoginEmailRequests: 10,
+				LoginEmailPer:      time.Minute,
+			},
+		},
+		AuthService:    authService,
+		RateLimitStore: store,
+	})
+	return router
+}
+
+func TestLoginLockout(t *testing.T) {
+	router := newRateLimitedRouter()
+	postJson(router, "/auth/register", auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+		Name:     "Vasya",
+	})
+	for i := 0; i < 3; i++ {
+		w := postJson(router, "/auth/login", auth.LoginRequest{Email: "a@a.ru", Password: "wrong"})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected %d, got %d", i+1, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// Даже верный пароль не проходит, пока аккаунт заблокирован
+	w := postJson(router, "/auth/login", auth.LoginRequest{Email: "a@a.ru", Password: "Secret123"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestLoginEmailRateLimit(t *testing.T) {
+	router := newRateLimitedRouter()
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 11; i++ {
+		w = postJson(router, "/auth/login", auth.LoginRequest{Email: "b@b.ru", Password: "x"})
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestAuthIpRateLimit(t *testing.T) {
+	router := newRateLimitedRouter()
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 21; i++ {
+		w = postJson(router, "/auth/refresh", auth.RefreshRequest{RefreshToken: "x"})
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestVerificationHandlers(t *testing.T) {
+	router := newTestRouter()
+	postJson(router, "/auth/register", auth.RegisterRequest{
+		Email:    "a@a.ru",
+		Password: "Secret123",
+		Name:     "Vasya",
+	})
+
+	testCases := []struct {
+		name    string
+		path    string
+		payload any
+		status  int
+	}{
+		{name: "Verify bad token", path: "/auth/verify-email", payload: auth.VerifyEmailRequest{Token: "bad"}, status: http.StatusBadRequest},
+		{name: "Forgot known", path: "/auth/forgot-password", payload: auth.ForgotPasswordRequest{Email: "a@a.ru"}, status: http.StatusAccepted},
+		{name: "Forgot unknown", path: "/auth/forgot-password", payload: auth.ForgotPasswordRequest{Email: "b@b.ru"}, status: http.StatusAccepted},
+		{name: "Reset bad token", path: "/auth/reset-password", payload: auth.ResetPasswordRequest{Token: "bad", Password: "Secret456"}, status: http.StatusBadRequest},
+		{name: "Reset weak password", path: "/auth/reset-password", payload: auth.ResetPasswordRequest{Token: "bad", Password: "weak"}, status: http.StatusUnprocessableEntity},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := postJson(router, tc.path, tc.payload)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEmailLinks(t *testing.T) {
+	repo := NewMockUserRepository()
+	authService := newTestAuthService(repo)
+	router := http.NewServeMux()
+	auth.NewHelloHandler(router, auth.AuthHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+		},
+		AuthService: authService,
+	})
+	// follow открывает ссылку из последнего письма так же, как браузер
+	follow := func() *httptest.ResponseRecorder {
+		messages := authService.Mailer.(*mailer.MemoryMailer).Messages()
+		link, err := url.Parse(linkRegexp.FindString(messages[len(messages)-1].Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+		return w
+	}
+
+	postJson(router, "/auth/register", auth.RegisterRequest{Email: "a@a.ru", Password: "Secret123", Name: "Vasya"})
+	w := follow()
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected verification page, got %d %s: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if u, _ := repo.FindByEmail("a@a.ru"); u.EmailVerifiedAt == nil {
+		t.Error("Email is not marked verified")
+	}
+	if w := follow(); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected used link to get %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	postJson(router, "/auth/forgot-password", auth.ForgotPasswordRequest{Email: "a@a.ru"})
+	w = follow()
+	token := regexp.MustCompile(`name="token" value="([^"]+)"`).FindStringSubmatch(w.Body.String())
+	if w.Code != http.StatusOK || token == nil {
+		t.Fatalf("Expected reset form with token, got %d: %s", w.Code, w.Body.String())
+	}
+	form := url.Values{"token": {token[1]}, "password": {"Secret456"}}
+	req := httptest.NewRequest(http.MethodPost, "/auth/reset-password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected result page, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := postJson(router, "/auth/login", auth.LoginRequest{Email: "a@a.ru", Password: "Secret456"}); w.Code != http.StatusOK {
+		t.Errorf("Expected login with the new password, got %d", w.Code)
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import "adv-mod/pkg/metrics"
+
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	LoginLocked  = "locked"
+)
+
+type AuthMetrics struct {
+	Logins        *metrics.Counter
+	Registrations *metrics.Counter
+}
+
+func NewAuthMetrics(registry *metrics.Registry) *AuthMetrics {
+	return &AuthMetrics{
+		Logins:        registry.NewCounter("auth_logins_total", "Login attempts by result: success, failure or locked.", "result"),
+		Registrations: registry.NewCounter("auth_registrations_total", "Successful registrations."),
+	}
+}
+
+func (m *AuthMetrics) login(result string) {
+	if m != nil {
+		m.Logins.Inc(result)
+	}
+}
+
+func (m *AuthMetrics) registered() {
+	if m != nil {
+		m.Registrations.Inc()
+	}
+}
@@ -0,0 +1,289 @@
+package auth_test
+
+import (
+	"adv-mod/internal/auth"
+	"adv-mod/internal/session"
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/mailer"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testSecret = "secret"
+
+// MockUserRepository хранит пользователей в памяти, чтобы тесты не требовали Postgres
+type MockUserRepository struct {
+	mu    sync.Mutex
+	users map[string]*user.User
+}
+
+func NewMockUserRepository() *MockUserRepository {
+	return &MockUserRepository{
+		users: map[string]*user.User{},
+	}
+}
+
+func (repo *MockUserRepository) Create(u *user.User) (*user.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, ok := repo.users[u.Email]; ok {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	u.ID = uint(len(repo.users) + 1)
+	repo.users[u.Email] = u
+	return u, nil
+}
+
+func (repo *MockUserRepository) FindByEmail(email string) (*user.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	u, ok := repo.users[email]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+func (repo *MockUserRepository) FindById(id uint) (*user.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, u := range repo.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *MockUserRepository) Update(u *user.User) (*user.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for email, existed := range repo.users {
+		if existed.ID == u.ID {
+			delete(repo.users, email)
+			repo.users[u.Email] = u
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *MockUserRepository) List(limit, offset int) ([]user.User, int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	users := make([]user.User, 0, len(repo.users))
+	for _, u := range repo.users {
+		users = append(users, *u)
+	}
+	slices.SortFunc(users, func(a, b user.User) int {
+		return int(a.ID) - int(b.ID)
+	})
+	total := int64(len(users))
+	users = users[min(offset, len(users)):]
+	return users[:min(limit, len(users))], total, nil
+}
+
+func (repo *MockUserRepository) UpdateRole(id uint, role string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, u := range repo.users {
+		if u.ID == id {
+			u.Role = role
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (repo *MockUserRepository) MarkEmailVerified(id uint, email string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	u, ok := repo.users[email]
+	if !ok || u.ID != id {
+		return false, nil
+	}
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	return true, nil
+}
+
+func (repo *MockUserRepository) SetPassword(id uint, hashedPassword string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, u := range repo.users {
+		if u.ID == id {
+			u.Password = hashedPassword
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (repo *MockUserRepository) ChangeEmail(id uint, email string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, ok := repo.users[email]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	for key, u := range repo.users {
+		if u.ID == id {
+			delete(repo.users, key)
+			u.Email = email
+			u.EmailVerifiedAt = nil
+			repo.users[email] = u
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (repo *MockUserRepository) Delete(id uint) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for key, u := range repo.users {
+		if u.ID == id {
+			delete(repo.users, key)
+			return nil
+		}
+	}
+	return nil
+}
+
+type MockRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*session.RefreshToken
+}
+
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{}
+}
+
+func (repo *MockRefreshTokenRepository) Create(t *session.RefreshToken) (*session.RefreshToken, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	t.ID = uint(len(repo.tokens) + 1)
+	repo.tokens = append(repo.tokens, t)
+	return t, nil
+}
+
+func (repo *MockRefreshTokenRepository) FindByHash(hash string) (*session.RefreshToken, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, t := range repo.tokens {
+		if t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *MockRefreshTokenRepository) MarkRotated(id uint) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, t := range repo.tokens {
+		if t.ID == id && t.RotatedAt == nil && t.RevokedAt == nil {
+			now := time.Now()
+			t.RotatedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *MockRefreshTokenRepository) RevokeFamily(familyId string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	now := time.Now()
+	for _, t := range repo.tokens {
+		if t.FamilyId == familyId && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (repo *MockRefreshTokenRepository) RevokeUser(userId uint) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	now := time.Now()
+	for _, t := range repo.tokens {
+		if t.UserId == userId && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+type MockVerificationTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*verification.Token
+}
+
+func NewMockVerificationTokenRepository() *MockVerificationTokenRepository {
+	return &MockVerificationTokenRepository{}
+}
+
+func (repo *MockVerificationTokenRepository) Create(t *verification.Token) (*verification.Token, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	t.ID = uint(len(repo.tokens) + 1)
+	repo.tokens = append(repo.tokens, t)
+	return t, nil
+}
+
+func (repo *MockVerificationTokenRepository) FindByHash(hash, purpose string) (*verification.Token, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, t := range repo.tokens {
+		if t.TokenHash == hash && t.Purpose == purpose {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *MockVerificationTokenRepository) MarkUsed(id uint) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, t := range repo.tokens {
+		if t.ID == id && t.UsedAt == nil {
+			now := time.Now()
+			t.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *MockVerificationTokenRepository) InvalidateUser(userId uint, purpose string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	now := time.Now()
+	for _, t := range repo.tokens {
+		if t.UserId == userId && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func newTestAuthService(userRepository *MockUserRepository) *auth.AuthService {
+	return auth.NewAuthService(auth.AuthServiceDeps{
+		UserRepository:              userRepository,
+		RefreshTokenRepository:      NewMockRefreshTokenRepository(),
+		JWT:                         jwt.NewJWT(testSecret),
+		RefreshTTL:                  time.Hour,
+		VerificationTokenRepository: NewMockVerificationTokenRepository(),
+		Mailer:                      mailer.NewMemoryMailer(),
+		VerifyEmailTTL:              time.Hour,
+		ResetPasswordTTL:            time.Hour,
+		BaseURL:                     "http://localhost:8081",
+	})
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"adv-mod/pkg/request"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+)
+
+// Ссылки из писем открываются в браузере, поэтому GET маршруты и отправка формы
+// сброса пароля отвечают HTML страницей, а не json
+var pages = template.Must(template.New("layout").Parse(`{{define "head"}}<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{end}}
+{{define "message"}}{{template "head" .}}<p>{{.Text}}</p>
+</body>
+</html>
+{{end}}
+{{define "reset"}}{{template "head" .}}<form method="post" action="/auth/reset-password">
+<input type="hidden" name="token" value="{{.Token}}">
+<label>Новый пароль <input type="password" name="password" minlength="{{.MinLength}}" autocomplete="new-password" required></label>
+<p>Не короче {{.MinLength}} символов, с заглавной и строчной буквой и цифрой.</p>
+<button type="submit">Сохранить</button>
+</form>
+</body>
+</html>
+{{end}}`))
+
+type page struct {
+	Title     string
+	Text      string
+	Token     string
+	MinLength int
+}
+
+func renderPage(w http.ResponseWriter, name string, data page, statusCode int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	err := pages.ExecuteTemplate(w, name, data)
+	if err != nil {
+		slog.Error("render page", "page", name, "error", err)
+	}
+}
+
+func renderMessage(w http.ResponseWriter, title, text string, statusCode int) {
+	renderPage(w, "message", page{Title: title, Text: text}, statusCode)
+}
+
+func renderResetForm(w http.ResponseWriter, token string) {
+	renderPage(w, "reset", page{
+		Title:     "Новый пароль",
+		Token:     token,
+		MinLength: request.PasswordMinLength,
+	}, http.StatusOK)
+}
+
+// isFormSubmit - запрос отправлен HTML формой, а не клиентом API
+func isFormSubmit(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded"
+}
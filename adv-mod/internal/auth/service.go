@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"adv-mod/internal/session"
+	"adv-mod/internal/user"
+	"adv-mod/pkg/di"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/mailer"
+	"adv-mod/pkg/ratelimit"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/token"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// dummyHash - bcrypt-хэш с той же стоимостью, что у настоящих паролей. С ним сверяется
+// пароль для неизвестного email, чтобы по времени ответа нельзя было узнать, есть ли аккаунт
+var dummyHash = []byte("$2a$10$anq/MCl2sZqJDD8pcCk0/uwiEzU4vgzaPud5bnqLBmfma9fdOAb/G")
+
+type AuthServiceDeps struct {
+	UserRepository         di.IUserRepository
+	RefreshTokenRepository di.IRefreshTokenRepository
+	JWT                    *jwt.JWT
+	RefreshTTL             time.Duration
+	Lockout                ratelimit.LockoutStore
+	LockoutPolicy          ratelimit.LockoutPolicy
+
+	VerificationTokenRepository di.IVerificationTokenRepository
+	Mailer                      mailer.Mailer
+	VerifyEmailTTL              time.Duration
+	ResetPasswordTTL            time.Duration
+	// BaseURL - адрес, от которого строятся ссылки в письмах
+	BaseURL string
+	Metrics *AuthMetrics
+}
+
+type AuthService struct {
+	UserRepository         di.IUserRepository
+	RefreshTokenRepository di.IRefreshTokenRepository
+	JWT                    *jwt.JWT
+	RefreshTTL             time.Duration
+	Lockout                ratelimit.LockoutStore
+	LockoutPolicy          ratelimit.LockoutPolicy
+
+	VerificationTokenRepository di.IVerificationTokenRepository
+	Mailer                      mailer.Mailer
+	VerifyEmailTTL              time.Duration
+	ResetPasswordTTL            time.Duration
+	// BaseURL - адрес, от которого строятся ссылки в письмах
+	BaseURL string
+	Metrics *AuthMetrics
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// RevokedSession - чья цепочка refresh токенов отозвана при выходе или при
+// повторном использовании токена
+type RevokedSession struct {
+	UserId   uint
+	Email    string
+	FamilyId string
+}
+
+func NewAuthService(deps AuthServiceDeps) *AuthService {
+	return &AuthService{
+		UserRepository:         deps.UserRepository,
+		RefreshTokenRepository: deps.RefreshTokenRepository,
+		JWT:                    deps.JWT,
+		RefreshTTL:             deps.RefreshTTL,
+		Lockout:                deps.Lockout,
+		LockoutPolicy:          deps.LockoutPolicy,
+
+		VerificationTokenRepository: deps.VerificationTokenRepository,
+		Mailer:                      deps.Mailer,
+		VerifyEmailTTL:              deps.VerifyEmailTTL,
+		ResetPasswordTTL:            deps.ResetPasswordTTL,
+		BaseURL:                     deps.BaseURL,
+		Metrics:                     deps.Metrics,
+	}
+}
+
+func (service *AuthService) Register(email, password, name string) (*TokenPair, error) {
+	existedUser, err := service.UserRepository.FindByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existedUser != nil {
+		return nil, ErrUserExists
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	newUser := &user.User{
+		Email:    email,
+		Password: string(hashedPassword),
+		Name:     name,
+		Role:     rbac.RoleUser,
+	}
+	_, err = service.UserRepository.Create(newUser)
+	// Уникальный индекс по email ловит гонку между двумя регистрациями
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrUserExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	service.Metrics.registered()
+	service.sendVerificationEmail(newUser)
+	return service.issueTokens(newUser, "")
+}
+
+// Login проверяет пароль. После LockoutPolicy.MaxFailures неудач подряд аккаунт
+// временно блокируется и возвращается *ratelimit.LockedError
+func (service *AuthService) Login(email, password string) (*TokenPair, error) {
+	lockoutKey := "login:" + strings.ToLower(email)
+	if service.Lockout != nil {
+		lockedFor, err := service.Lockout.LockedFor(lockoutKey)
+		if err != nil {
+			return nil, err
+		}
+		if lockedFor > 0 {
+			service.Metrics.login(LoginLocked)
+			return nil, &ratelimit.LockedError{RetryAfter: lockedFor}
+		}
+	}
+	existedUser, err := service.UserRepository.FindByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	hashedPassword := dummyHash
+	if existedUser != nil {
+		hashedPassword = []byte(existedUser.Password)
+	}
+	// Неизвестный email тоже считается неудачей, иначе блокировка выдаёт, какие аккаунты существуют
+	if bcrypt.CompareHashAndPassword(hashedPassword, []byte(password)) != nil || existedUser == nil {
+		service.Metrics.login(LoginFailure)
+		return nil, service.loginFailed(lockoutKey)
+	}
+	if service.Lockout != nil {
+		err = service.Lockout.Reset(lockoutKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	service.Metrics.login(LoginSuccess)
+	return service.issueTokens(existedUser, "")
+}
+
+func (service *AuthService) loginFailed(lockoutKey string) error {
+	if service.Lockout == nil {
+		return ErrWrongCredentials
+	}
+	_, err := service.Lockout.RegisterFailure(lockoutKey, service.LockoutPolicy)
+	if err != nil {
+		return err
+	}
+	return ErrWrongCredentials
+}
+
+// Refresh обменивает refresh токен на новую пару. Каждый refresh токен одноразовый:
+// повторное предъявление уже использованного означает утечку, и вся цепочка отзывается.
+// Вместе с ErrRefreshTokenReused возвращается отозванная сессия
+func (service *AuthService) Refresh(refreshToken string) (*TokenPair, *RevokedSession, error) {
+	stored, err := service.RefreshTokenRepository.FindByHash(token.Hash(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if stored.RevokedAt != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if stored.RotatedAt != nil {
+		return service.revokeReused(stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, nil, ErrRefreshTokenExpired
+	}
+	rotated, err := service.RefreshTokenRepository.MarkRotated(stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		return service.revokeReused(stored)
+	}
+	existedUser, err := service.UserRepository.FindById(stored.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := service.issueTokens(existedUser, stored.FamilyId)
+	return tokens, nil, err
+}
+
+// Logout отзывает сессию, которой принадлежит refresh токен. Неизвестный или уже
+// отозванный токен не ошибка, тогда сессия nil
+func (service *AuthService) Logout(refreshToken string) (*RevokedSession, error) {
+	stored, err := service.RefreshTokenRepository.FindByHash(token.Hash(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.RevokedAt != nil {
+		return nil, nil
+	}
+	err = service.RefreshTokenRepository.RevokeFamily(stored.FamilyId)
+	if err != nil {
+		return nil, err
+	}
+	return service.revokedSession(stored)
+}
+
+func (service *AuthService) revokeReused(stored *session.RefreshToken) (*TokenPair, *RevokedSession, error) {
+	err := service.RefreshTokenRepository.RevokeFamily(stored.FamilyId)
+	if err != nil {
+		return nil, nil, err
+	}
+	revoked, err := service.revokedSession(stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, revoked, ErrRefreshTokenReused
+}
+
+// revokedSession дополняет отозванную сессию email владельца. Удалённый
+// пользователь не ошибка, email остаётся пустым
+func (service *AuthService) revokedSession(stored *session.RefreshToken) (*RevokedSession, error) {
+	revoked := &RevokedSession{UserId: stored.UserId, FamilyId: stored.FamilyId}
+	owner, err := service.UserRepository.FindById(stored.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return revoked, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	revoked.Email = owner.Email
+	return revoked, nil
+}
+
+// issueTokens выдаёт access и refresh токены. Пустой familyId начинает новую сессию
+func (service *AuthService) issueTokens(u *user.User, familyId string) (*TokenPair, error) {
+	accessToken, err := service.JWT.Create(jwt.JWTData{
+		Email:  u.Email,
+		UserId: u.ID,
+		Role:   u.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+	if familyId == "" {
+		familyId, err = token.Generate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = service.RefreshTokenRepository.Create(&session.RefreshToken{
+		UserId:    u.ID,
+		FamilyId:  familyId,
+		TokenHash: token.Hash(refreshToken),
+		ExpiresAt: time.Now().Add(service.RefreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    service.JWT.TTL,
+	}, nil
+}
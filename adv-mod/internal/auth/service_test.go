@@ -0,0 +1,157 @@
+package auth_test
+
+import (
+	"adv-mod/internal/auth"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/metrics"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegisterSuccess(t *testing.T) {
+	repo := NewMockUserRepository()
+	authService := newTestAuthService(repo)
+	tokens, err := authService.Register("a@a.ru", "secret", "Vasya")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := jwt.NewJWT(testSecret).Parse(tokens.AccessToken)
+	if err != nil || data.Email != "a@a.ru" {
+		t.Errorf("Unexpected access token %v: %v", data, err)
+	}
+	stored, _ := repo.FindByEmail("a@a.ru")
+	if stored.Password == "secret" {
+		t.Fatal("Password stored in plain text")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("secret")); err != nil {
+		t.Errorf("Stored hash does not match password: %v", err)
+	}
+}
+
+func TestRegisterExisted(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	_, err := authService.Register("a@a.ru", "secret", "Vasya")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = authService.Register("a@a.ru", "other", "Petya")
+	if !errors.Is(err, auth.ErrUserExists) {
+		t.Errorf("Expected %v, got %v", auth.ErrUserExists, err)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	first, err := authService.Register("a@a.ru", "secret", "Vasya")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := authService.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh token was not rotated")
+	}
+	if _, err := authService.JWT.Parse(second.AccessToken); err != nil {
+		t.Errorf("New access token is invalid: %v", err)
+	}
+	if _, _, err := authService.Refresh(second.RefreshToken); err != nil {
+		t.Errorf("Rotated token must be usable once: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	first, _ := authService.Register("a@a.ru", "secret", "Vasya")
+	second, _, err := authService.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, revoked, err := authService.Refresh(first.RefreshToken)
+	if !errors.Is(err, auth.ErrRefreshTokenReused) {
+		t.Fatalf("Expected %v, got %v", auth.ErrRefreshTokenReused, err)
+	}
+	if revoked == nil || revoked.UserId == 0 || revoked.Email != "a@a.ru" || revoked.FamilyId == "" {
+		t.Errorf("Expected revoked session of a@a.ru, got %+v", revoked)
+	}
+	_, _, err = authService.Refresh(second.RefreshToken)
+	if !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("Expected whole family to be revoked, got %v", err)
+	}
+}
+
+func TestRefreshReuseKeepsOtherSessions(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	first, _ := authService.Register("a@a.ru", "secret", "Vasya")
+	other, _ := authService.Login("a@a.ru", "secret")
+	authService.Refresh(first.RefreshToken)
+	authService.Refresh(first.RefreshToken)
+
+	if _, _, err := authService.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("Other session must survive reuse detection: %v", err)
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	authService.RefreshTTL = -time.Minute
+	tokens, _ := authService.Register("a@a.ru", "secret", "Vasya")
+
+	_, _, err := authService.Refresh(tokens.RefreshToken)
+	if !errors.Is(err, auth.ErrRefreshTokenExpired) {
+		t.Errorf("Expected %v, got %v", auth.ErrRefreshTokenExpired, err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	tokens, _ := authService.Register("a@a.ru", "secret", "Vasya")
+	revoked, err := authService.Logout(tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked == nil || revoked.Email != "a@a.ru" {
+		t.Errorf("Expected revoked session of a@a.ru, got %+v", revoked)
+	}
+	_, _, err = authService.Refresh(tokens.RefreshToken)
+	if !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("Expected %v, got %v", auth.ErrInvalidRefreshToken, err)
+	}
+	for _, refreshToken := range []string{tokens.RefreshToken, "unknown"} {
+		revoked, err := authService.Logout(refreshToken)
+		if err != nil || revoked != nil {
+			t.Errorf("Expected nothing to revoke, got %+v, %v", revoked, err)
+		}
+	}
+}
+
+func TestAuthMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	authService := newTestAuthService(NewMockUserRepository())
+	authService.Metrics = auth.NewAuthMetrics(registry)
+
+	authService.Register("a@a.ru", "secret", "Vasya")
+	authService.Login("a@a.ru", "secret")
+	authService.Login("a@a.ru", "wrong")
+	authService.Login("b@a.ru", "secret")
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`auth_logins_total{result="success"} 1`,
+		`auth_logins_total{result="failure"} 2`,
+		`auth_registrations_total 1`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+	"adv-mod/pkg/mailer"
+	"adv-mod/pkg/token"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// SendVerificationEmail отправляет письмо со ссылкой подтверждения. Предыдущие ссылки перестают работать
+func (service *AuthService) SendVerificationEmail(u *user.User) error {
+	raw, err := service.createToken(u, verification.PurposeVerifyEmail, service.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return service.Mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Подтвердите email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить email, перейдите по ссылке:\n%s\n\nСсылка действует %s.\n",
+			u.Name, service.link("auth/verify-email", raw), service.VerifyEmailTTL),
+	})
+}
+
+func (service *AuthService) VerifyEmail(rawToken string) error {
+	existedUser, err := service.consumeToken(rawToken, verification.PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	// Пишется только отметка и только для адреса, который был у пользователя сейчас:
+	// параллельная смена email не должна получить чужое подтверждение
+	verified, err := service.UserRepository.MarkEmailVerified(existedUser.ID, existedUser.Email)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+// ForgotPassword молча ничего не делает для неизвестного email,
+// чтобы ответ не раскрывал, зарегистрирован ли адрес
+func (service *AuthService) ForgotPassword(email string) error {
+	existedUser, err := service.UserRepository.FindByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	raw, err := service.createToken(existedUser, verification.PurposeResetPassword, service.ResetPasswordTTL)
+	if err != nil {
+		return err
+	}
+	return service.Mailer.Send(mailer.Message{
+		To:      existedUser.Email,
+		Subject: "Восстановление пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\nСсылка действует %s. Если вы не запрашивали восстановление, просто проигнорируйте письмо.\n",
+			existedUser.Name, service.link("auth/reset-password", raw), service.ResetPasswordTTL),
+	})
+}
+
+// ResetPassword задаёт новый пароль и завершает все сессии пользователя.
+// Возвращает пользователя, чей пароль изменён
+func (service *AuthService) ResetPassword(rawToken, password string) (*user.User, error) {
+	existedUser, err := service.consumeToken(rawToken, verification.PurposeResetPassword)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	err = service.UserRepository.SetPassword(existedUser.ID, string(hashedPassword))
+	if err != nil {
+		return nil, err
+	}
+	err = service.RefreshTokenRepository.RevokeUser(existedUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if service.Lockout != nil {
+		err = service.Lockout.Reset("login:" + strings.ToLower(existedUser.Email))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return existedUser, nil
+}
+
+func (service *AuthService) createToken(u *user.User, purpose string, ttl time.Duration) (string, error) {
+	err := service.VerificationTokenRepository.InvalidateUser(u.ID, purpose)
+	if err != nil {
+		return "", err
+	}
+	raw, err := token.Generate()
+	if err != nil {
+		return "", err
+	}
+	_, err = service.VerificationTokenRepository.Create(&verification.Token{
+		UserId:    u.ID,
+		Purpose:   purpose,
+		Email:     u.Email,
+		TokenHash: token.Hash(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// consumeToken гасит токен и возвращает его владельца. Токен, выданный на
+// прежний email пользователя, недействителен
+func (service *AuthService) consumeToken(raw, purpose string) (*user.User, error) {
+	stored, err := service.VerificationTokenRepository.FindByHash(token.Hash(raw), purpose)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+	used, err := service.VerificationTokenRepository.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidVerificationToken
+	}
+	existedUser, err := service.UserRepository.FindById(stored.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.Email != existedUser.Email {
+		return nil, ErrInvalidVerificationToken
+	}
+	return existedUser, nil
+}
+
+// link строит ссылку на GET маршруты /auth/verify-email и /auth/reset-password,
+// которые отвечают HTML страницей
+func (service *AuthService) link(path, raw string) string {
+	return strings.TrimRight(service.BaseURL, "/") + "/" + path + "?token=" + url.QueryEscape(raw)
+}
+
+// sendVerificationEmail только логирует ошибку: сбой почты не должен срывать регистрацию
+func (service *AuthService) sendVerificationEmail(u *user.User) {
+	err := service.SendVerificationEmail(u)
+	if err != nil {
+		slog.Error("send verification email", "error", err, "user_id", u.ID)
+	}
+}
@@ -0,0 +1,166 @@
+package auth_test
+
+import (
+	"adv-mod/internal/auth"
+	"adv-mod/pkg/mailer"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var linkRegexp = regexp.MustCompile(`https?://\S+`)
+
+// lastToken достаёт токен из ссылки в последнем отправленном письме
+func lastToken(t *testing.T, authService *auth.AuthService) string {
+	t.Helper()
+	messages := authService.Mailer.(*mailer.MemoryMailer).Messages()
+	if len(messages) == 0 {
+		t.Fatal("No mail was sent")
+	}
+	link := linkRegexp.FindString(messages[len(messages)-1].Body)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Bad link %q: %v", link, err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestVerifyEmail(t *testing.T) {
+	repo := NewMockUserRepository()
+	authService := newTestAuthService(repo)
+	authService.Register("a@a.ru", "Secret123", "Vasya")
+	raw := lastToken(t, authService)
+
+	if err := authService.VerifyEmail(raw); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u, _ := repo.FindByEmail("a@a.ru")
+	if u.EmailVerifiedAt == nil {
+		t.Error("Email is not marked verified")
+	}
+	if err := authService.VerifyEmail(raw); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("Token must be single-use, got %v", err)
+	}
+}
+
+// Ссылка доказывает доступ только к ящику, куда её отправили. После смены email
+// старая ссылка не должна ни подтвердить новый адрес, ни сбросить пароль
+func TestLinksRejectedAfterEmailChange(t *testing.T) {
+	testCases := []struct {
+		name string
+		send func(authService *auth.AuthService) error
+		use  func(authService *auth.AuthService, raw string) error
+	}{
+		{
+			name: "Verify email",
+			send: func(authService *auth.AuthService) error { return nil },
+			use:  func(authService *auth.AuthService, raw string) error { return authService.VerifyEmail(raw) },
+		},
+		{
+			name: "Reset password",
+			send: func(authService *auth.AuthService) error { return authService.ForgotPassword("a@a.ru") },
+			use: func(authService *auth.AuthService, raw string) error {
+				_, err := authService.ResetPassword(raw, "NewSecret456")
+				return err
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewMockUserRepository()
+			authService := newTestAuthService(repo)
+			authService.Register("a@a.ru", "Secret123", "Vasya")
+			if err := tc.send(authService); err != nil {
+				t.Fatal(err)
+			}
+			raw := lastToken(t, authService)
+			u, _ := repo.FindByEmail("a@a.ru")
+			if err := repo.ChangeEmail(u.ID, "admin@a.ru"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tc.use(authService, raw); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+				t.Errorf("Expected %v, got %v", auth.ErrInvalidVerificationToken, err)
+			}
+			if u.EmailVerifiedAt != nil {
+				t.Error("New email must stay unverified")
+			}
+			if _, err := authService.Login("admin@a.ru", "Secret123"); err != nil {
+				t.Errorf("Password must stay unchanged: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyEmailExpired(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	authService.VerifyEmailTTL = -time.Minute
+	authService.Register("a@a.ru", "Secret123", "Vasya")
+
+	err := authService.VerifyEmail(lastToken(t, authService))
+	if !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("Expected %v, got %v", auth.ErrInvalidVerificationToken, err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	session, _ := authService.Register("a@a.ru", "Secret123", "Vasya")
+
+	if err := authService.ForgotPassword("a@a.ru"); err != nil {
+		t.Fatal(err)
+	}
+	raw := lastToken(t, authService)
+	u, err := authService.ResetPassword(raw, "NewSecret456")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if u.Email != "a@a.ru" {
+		t.Errorf("Expected a@a.ru, got %s", u.Email)
+	}
+	// Сброс доказывает доступ к ящику, куда ушло письмо, а не к текущему email аккаунта
+	if u.EmailVerifiedAt != nil {
+		t.Error("Reset must not mark the email verified")
+	}
+
+	if _, err := authService.Login("a@a.ru", "Secret123"); !errors.Is(err, auth.ErrWrongCredentials) {
+		t.Errorf("Old password still works: %v", err)
+	}
+	if _, err := authService.Login("a@a.ru", "NewSecret456"); err != nil {
+		t.Errorf("New password does not work: %v", err)
+	}
+	if _, _, err := authService.Refresh(session.RefreshToken); err == nil {
+		t.Error("Sessions must be revoked after reset")
+	}
+	if _, err := authService.ResetPassword(raw, "Other789abc"); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("Token must be single-use, got %v", err)
+	}
+}
+
+func TestForgotPasswordOnlyLatestLinkWorks(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	authService.Register("a@a.ru", "Secret123", "Vasya")
+	authService.ForgotPassword("a@a.ru")
+	first := lastToken(t, authService)
+	authService.ForgotPassword("a@a.ru")
+	second := lastToken(t, authService)
+
+	if _, err := authService.ResetPassword(first, "NewSecret456"); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("Older link must be invalidated, got %v", err)
+	}
+	if _, err := authService.ResetPassword(second, "NewSecret456"); err != nil {
+		t.Errorf("Latest link must work: %v", err)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	authService := newTestAuthService(NewMockUserRepository())
+	if err := authService.ForgotPassword("nobody@a.ru"); err != nil {
+		t.Errorf("Unknown email must not be reported, got %v", err)
+	}
+	if n := len(authService.Mailer.(*mailer.MemoryMailer).Messages()); n != 0 {
+		t.Errorf("Expected no mail, got %d", n)
+	}
+}
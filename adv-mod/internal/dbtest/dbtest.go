@@ -0,0 +1,32 @@
+// Package dbtest открывает SQLite в памяти для тестов, которым нужна настоящая БД
+package dbtest
+
+import (
+	"adv-mod/pkg/db"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open создаёт пустую базу в памяти и строит схему моделей через AutoMigrate.
+// SQL миграции написаны под Postgres, что они совпадают с моделями, проверяет
+// migrations/schema_test.go. База закрывается вместе с тестом
+func Open(t testing.TB, models ...any) *db.Db {
+	t.Helper()
+	gormDb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Каждое соединение к :memory: видит свою базу, поэтому оставляем одно
+	sqlDb, err := gormDb.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDb.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := gormDb.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return &db.Db{DB: gormDb}
+}
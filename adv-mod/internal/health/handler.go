@@ -0,0 +1,117 @@
+package health
+
+import (
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/response"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout ограничивает каждую проверку готовности
+const DefaultTimeout = 2 * time.Second
+
+// Причина отказа уходит в лог, а /readyz доступен без авторизации, поэтому
+// наружу отдаётся только обобщённое сообщение без адресов и учётных данных
+const (
+	ErrorTimeout = "timeout"
+	ErrorFailed  = "check failed"
+)
+
+// Pinger - зависимость, без которой сервис не готов принимать трафик
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type HealthHandlerDeps struct {
+	Checks  map[string]Pinger
+	Timeout time.Duration
+	OpenAPI *openapi.Registry
+}
+
+type HealthHandler struct {
+	Checks  map[string]Pinger
+	Timeout time.Duration
+}
+
+func NewHelloHandler(router *http.ServeMux, deps HealthHandlerDeps) {
+	handler := &HealthHandler{
+		Checks:  deps.Checks,
+		Timeout: deps.Timeout,
+	}
+	if handler.Timeout <= 0 {
+		handler.Timeout = DefaultTimeout
+	}
+	deps.OpenAPI.Handle(router, "GET /healthz", handler.Live(), openapi.Operation{
+		Summary:   "Liveness probe",
+		Tags:      []string{"health"},
+		Responses: map[int]any{200: HealthResponse{}},
+	})
+	deps.OpenAPI.Handle(router, "GET /readyz", handler.Ready(), openapi.Operation{
+		Summary:   "Readiness probe, checks the database",
+		Tags:      []string{"health"},
+		Responses: map[int]any{200: HealthResponse{}, 503: HealthResponse{}},
+	})
+}
+
+// Live отвечает, пока процесс обслуживает запросы, и не трогает зависимости
+func (handler *HealthHandler) Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.Json(w, HealthResponse{Status: StatusOk}, http.StatusOK)
+	}
+}
+
+// Ready опрашивает зависимости параллельно и отвечает 503, если хоть одна недоступна
+func (handler *HealthHandler) Ready() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), handler.Timeout)
+		defer cancel()
+
+		type named struct {
+			name   string
+			result CheckResult
+		}
+		results := make(chan named, len(handler.Checks))
+		for name, pinger := range handler.Checks {
+			go func() {
+				results <- named{name, check(ctx, name, pinger)}
+			}()
+		}
+		data := HealthResponse{
+			Status: StatusOk,
+			Checks: make(map[string]CheckResult, len(handler.Checks)),
+		}
+		for range handler.Checks {
+			item := <-results
+			data.Checks[item.name] = item.result
+			if item.result.Status != StatusOk {
+				data.Status = StatusUnavailable
+			}
+		}
+		status := http.StatusOK
+		if data.Status != StatusOk {
+			status = http.StatusServiceUnavailable
+		}
+		response.Json(w, data, status)
+	}
+}
+
+func check(ctx context.Context, name string, pinger Pinger) CheckResult {
+	start := time.Now()
+	err := pinger.Ping(ctx)
+	result := CheckResult{
+		Status:    StatusOk,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		slog.Warn("readiness check failed", "check", name, "error", err)
+		result.Status = StatusUnavailable
+		result.Error = ErrorFailed
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = ErrorTimeout
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package health_test
+
+import (
+	"adv-mod/internal/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type PingerFunc func(ctx context.Context) error
+
+func (f PingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func newTestRouter(checks map[string]health.Pinger) *http.ServeMux {
+	router := http.NewServeMux()
+	health.NewHelloHandler(router, health.HealthHandlerDeps{
+		Checks:  checks,
+		Timeout: 50 * time.Millisecond,
+	})
+	return router
+}
+
+func get(t *testing.T, router http.Handler, path string) (int, health.HealthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var resp health.HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, resp
+}
+
+func TestLive(t *testing.T) {
+	router := newTestRouter(map[string]health.Pinger{
+		"database": PingerFunc(func(ctx context.Context) error {
+			t.Error("Liveness must not check dependencies")
+			return nil
+		}),
+	})
+	status, resp := get(t, router, "/healthz")
+	if status != http.StatusOK || resp.Status != health.StatusOk {
+		t.Errorf("Expected 200 ok, got %d %q", status, resp.Status)
+	}
+}
+
+func TestReady(t *testing.T) {
+	ok := PingerFunc(func(ctx context.Context) error { return nil })
+	down := PingerFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	hang := PingerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	testCases := []struct {
+		name   string
+		checks map[string]health.Pinger
+		status int
+		failed string
+		error  string
+	}{
+		{name: "Ready", checks: map[string]health.Pinger{"database": ok}, status: http.StatusOK},
+		{name: "Down", checks: map[string]health.Pinger{"database": down, "cache": ok}, status: http.StatusServiceUnavailable, failed: "database", error: health.ErrorFailed},
+		{name: "Timeout", checks: map[string]health.Pinger{"database": hang}, status: http.StatusServiceUnavailable, failed: "database", error: health.ErrorTimeout},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, resp := get(t, newTestRouter(tc.checks), "/readyz")
+			if status != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, status)
+			}
+			if len(resp.Checks) != len(tc.checks) {
+				t.Errorf("Expected %d checks, got %+v", len(tc.checks), resp.Checks)
+			}
+			if tc.failed != "" {
+				check := resp.Checks[tc.failed]
+				if check.Status != health.StatusUnavailable || check.Error != tc.error {
+					t.Errorf("Expected %s to be unavailable with %q, got %+v", tc.failed, tc.error, check)
+				}
+			}
+		})
+	}
+}
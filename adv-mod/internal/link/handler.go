@@ -0,0 +1,228 @@
+package link
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/db"
+	"adv-mod/pkg/event"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/request"
+	"adv-mod/pkg/response"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LinkHandlerDeps struct {
+	*configs.Config
+	LinkRepository *LinkRepository
+	EventBus       *event.Bus
+	OpenAPI        *openapi.Registry
+}
+
+type LinkHandler struct {
+	*configs.Config
+	LinkRepository *LinkRepository
+	EventBus       *event.Bus
+}
+
+// NewHelloHandler регистрирует CRUD ссылок текущего пользователя и публичный редирект GET /{hash}
+func NewHelloHandler(router *http.ServeMux, deps LinkHandlerDeps) {
+	handler := &LinkHandler{
+		Config:         deps.Config,
+		LinkRepository: deps.LinkRepository,
+		EventBus:       deps.EventBus,
+	}
+	api := deps.OpenAPI
+	canRead := middleware.RequireScope(rbac.PermissionLinksRead)
+	canWrite := middleware.RequireScope(rbac.PermissionLinksWrite)
+	failure := response.ErrorBody{}
+	api.Handle(router, "POST /link", middleware.IsAuthed(canWrite(handler.Create()), deps.Config), openapi.Operation{
+		Summary:   "Create a short link",
+		Tags:      []string{"link"},
+		Request:   LinkCreateRequest{},
+		Responses: map[int]any{201: LinkResponse{}, 400: failure, 401: failure, 403: failure, 422: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	api.Handle(router, "GET /link", middleware.IsAuthed(canRead(handler.List()), deps.Config), openapi.Operation{
+		Summary: "List links of the current user",
+		Tags:    []string{"link"},
+		Query: []openapi.Parameter{
+			{Name: "limit", Type: "integer", Description: "page size, up to " + strconv.Itoa(db.MaxLimit)},
+			{Name: "offset", Type: "integer"},
+			{Name: "cursor", Description: "next_cursor of the previous page"},
+			{Name: "sort", Description: "comma separated id, created_at, url; prefix - for descending order"},
+			{Name: "url", Description: "filter, supports url[like]=%example%"},
+			{Name: "hash"},
+		},
+		Responses: map[int]any{200: ListLinksResponse{}, 400: failure, 401: failure, 403: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	api.Handle(router, "GET /link/{id}", middleware.IsAuthed(canRead(handler.Get()), deps.Config), openapi.Operation{
+		Summary:   "Get a link of the current user",
+		Tags:      []string{"link"},
+		Responses: map[int]any{200: LinkResponse{}, 400: failure, 401: failure, 403: failure, 404: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	api.Handle(router, "PATCH /link/{id}", middleware.IsAuthed(canWrite(handler.Update()), deps.Config), openapi.Operation{
+		Summary:   "Change the target url of a link",
+		Tags:      []string{"link"},
+		Request:   LinkUpdateRequest{},
+		Responses: map[int]any{200: LinkResponse{}, 400: failure, 401: failure, 403: failure, 404: failure, 422: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	api.Handle(router, "DELETE /link/{id}", middleware.IsAuthed(canWrite(handler.Delete()), deps.Config), openapi.Operation{
+		Summary:   "Delete a link",
+		Tags:      []string{"link"},
+		Responses: map[int]any{204: nil, 400: failure, 401: failure, 403: failure, 404: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+	api.Handle(router, "GET /{hash}", handler.GoTo(), openapi.Operation{
+		Summary:   "Redirect to the original url",
+		Tags:      []string{"link"},
+		Responses: map[int]any{307: nil, 404: failure},
+	})
+}
+
+func (handler *LinkHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		body, err := request.HandleBody[LinkCreateRequest](&w, r)
+		if err != nil {
+			return
+		}
+		created, err := handler.LinkRepository.CreateWithHash(NewLink(body.Url, userId), GenerateHash)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		response.Json(w, NewLinkResponse(created), http.StatusCreated)
+	}
+}
+
+func (handler *LinkHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		query, err := handler.LinkRepository.ParseQuery(r.URL.Query())
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		page, err := handler.LinkRepository.List(query, OwnedBy(userId))
+		if errors.Is(err, db.ErrInvalidQuery) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		data := ListLinksResponse{
+			Items:      make([]LinkResponse, 0, len(page.Items)),
+			Total:      page.Total,
+			NextCursor: page.NextCursor,
+			Limit:      page.Limit,
+			Offset:     page.Offset,
+		}
+		for i := range page.Items {
+			data.Items = append(data.Items, NewLinkResponse(&page.Items[i]))
+		}
+		response.Json(w, data, http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, ok := handler.findOwned(w, r)
+		if !ok {
+			return
+		}
+		response.Json(w, NewLinkResponse(l), http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, ok := handler.findOwned(w, r)
+		if !ok {
+			return
+		}
+		body, err := request.HandleBody[LinkUpdateRequest](&w, r)
+		if err != nil {
+			return
+		}
+		l.Url = body.Url
+		updated, err := handler.LinkRepository.Update(l)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		response.Json(w, NewLinkResponse(updated), http.StatusOK)
+	}
+}
+
+func (handler *LinkHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, ok := handler.findOwned(w, r)
+		if !ok {
+			return
+		}
+		err := handler.LinkRepository.Delete(l.ID)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GoTo - публичный редирект. 307 сохраняет метод и не кэшируется браузером
+// навсегда, поэтому смена url ссылки сразу вступает в силу
+func (handler *LinkHandler) GoTo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := handler.LinkRepository.FindByHash(r.PathValue("hash"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(w, "link not found")
+			return
+		}
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		event.Publish(handler.EventBus, TopicVisited, LinkVisited{LinkId: l.ID, VisitedAt: time.Now()})
+		http.Redirect(w, r, l.Url, http.StatusTemporaryRedirect)
+	}
+}
+
+// findOwned пишет ответ об ошибке сам и возвращает false, если ссылки нет
+func (handler *LinkHandler) findOwned(w http.ResponseWriter, r *http.Request) (*Link, bool) {
+	userId, ok := middleware.RequireUserId(w, r)
+	if !ok {
+		return nil, false
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid link id")
+		return nil, false
+	}
+	l, err := handler.LinkRepository.FindOwned(uint(id), userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.NotFound(w, "link not found")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalServerError(w, err)
+		return nil, false
+	}
+	return l, true
+}
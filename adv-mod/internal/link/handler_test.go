@@ -0,0 +1,279 @@
+package link_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/dbtest"
+	"adv-mod/internal/link"
+	"adv-mod/pkg/event"
+	"adv-mod/pkg/jwt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+const testSecret = "secret"
+
+func newTestRepository(t *testing.T) *link.LinkRepository {
+	t.Helper()
+	return link.NewLinkRepository(dbtest.Open(t, &link.Link{}))
+}
+
+func newTestRouter(repo *link.LinkRepository) *http.ServeMux {
+	router := http.NewServeMux()
+	link.NewHelloHandler(router, link.LinkHandlerDeps{
+		Config: &configs.Config{
+			Auth: configs.AuthConfig{Secret: testSecret},
+		},
+		LinkRepository: repo,
+	})
+	return router
+}
+
+func createLink(t *testing.T, repo *link.LinkRepository, url, hash string, userId uint) *link.Link {
+	t.Helper()
+	l, err := repo.Create(&link.Link{Url: url, Hash: hash, UserId: userId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// serve с userId 0 отправляет запрос без токена
+func serve(router http.Handler, method, path string, userId uint, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	if userId != 0 {
+		token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "a@a.ru", UserId: userId})
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGenerateHash(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	seen := map[string]bool{}
+	for range 100 {
+		hash := link.GenerateHash()
+		if len(hash) != link.HashLength || !valid.MatchString(hash) {
+			t.Fatalf("Unexpected hash %q", hash)
+		}
+		seen[hash] = true
+	}
+	if len(seen) < 99 {
+		t.Errorf("Expected random hashes, got %d unique of 100", len(seen))
+	}
+}
+
+func TestCreateWithHashRetriesOnCollision(t *testing.T) {
+	repo := newTestRepository(t)
+	createLink(t, repo, "https://a.ru", "taken1", 1)
+	createLink(t, repo, "https://b.ru", "taken2", 1)
+
+	hashes := []string{"taken1", "taken2", "free01"}
+	generate := func() string {
+		hash := hashes[0]
+		hashes = hashes[1:]
+		return hash
+	}
+	created, err := repo.CreateWithHash(link.NewLink("https://c.ru", 1), generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Hash != "free01" || created.ID == 0 {
+		t.Errorf("Expected link with free hash, got %+v", created)
+	}
+}
+
+func TestCreateWithHashGivesUp(t *testing.T) {
+	repo := newTestRepository(t)
+	createLink(t, repo, "https://a.ru", "taken1", 1)
+
+	_, err := repo.CreateWithHash(link.NewLink("https://c.ru", 1), func() string { return "taken1" })
+	if !errors.Is(err, link.ErrHashCollision) {
+		t.Errorf("Expected ErrHashCollision, got %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		userId  uint
+		payload any
+		status  int
+	}{
+		{name: "Success", userId: 1, payload: map[string]string{"url": "https://example.com/page?q=1"}, status: http.StatusCreated},
+		{name: "Not a url", userId: 1, payload: map[string]string{"url": "example"}, status: http.StatusUnprocessableEntity},
+		{name: "Not http", userId: 1, payload: map[string]string{"url": "ftp://example.com"}, status: http.StatusUnprocessableEntity},
+		{name: "Empty url", userId: 1, payload: map[string]string{}, status: http.StatusUnprocessableEntity},
+		{name: "Without token", payload: map[string]string{"url": "https://example.com"}, status: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newTestRouter(newTestRepository(t))
+			w := serve(router, http.MethodPost, "/link", tc.userId, tc.payload)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusCreated {
+				return
+			}
+			var resp link.LinkResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Hash) != link.HashLength || resp.Url != "https://example.com/page?q=1" {
+				t.Errorf("Unexpected link %+v", resp)
+			}
+		})
+	}
+}
+
+func TestListReturnsOwnLinks(t *testing.T) {
+	repo := newTestRepository(t)
+	router := newTestRouter(repo)
+	createLink(t, repo, "https://a.ru", "aaaaaa", 1)
+	createLink(t, repo, "https://b.ru", "bbbbbb", 2)
+	createLink(t, repo, "https://c.ru", "cccccc", 1)
+
+	w := serve(router, http.MethodGet, "/link", 1, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp link.ListLinksResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total == nil || *resp.Total != 2 || len(resp.Items) != 2 {
+		t.Fatalf("Expected 2 own links, got %+v", resp)
+	}
+	// По умолчанию новые первыми
+	if resp.Items[0].Hash != "cccccc" || resp.Items[1].Hash != "aaaaaa" {
+		t.Errorf("Unexpected order %+v", resp.Items)
+	}
+
+	w = serve(router, http.MethodGet, "/link?sort=password", 1, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d for unknown sort, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		userId  uint
+		payload any
+		status  int
+	}{
+		{name: "Get own", method: http.MethodGet, userId: 1, status: http.StatusOK},
+		{name: "Get foreign", method: http.MethodGet, userId: 2, status: http.StatusNotFound},
+		{name: "Update own", method: http.MethodPatch, userId: 1, payload: map[string]string{"url": "https://new.ru"}, status: http.StatusOK},
+		{name: "Update foreign", method: http.MethodPatch, userId: 2, payload: map[string]string{"url": "https://new.ru"}, status: http.StatusNotFound},
+		{name: "Update invalid url", method: http.MethodPatch, userId: 1, payload: map[string]string{"url": "nope"}, status: http.StatusUnprocessableEntity},
+		{name: "Delete own", method: http.MethodDelete, userId: 1, status: http.StatusNoContent},
+		{name: "Delete foreign", method: http.MethodDelete, userId: 2, status: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			router := newTestRouter(repo)
+			l := createLink(t, repo, "https://a.ru", "aaaaaa", 1)
+
+			w := serve(router, tc.method, "/link/"+strconv.Itoa(int(l.ID)), tc.userId, tc.payload)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			stored, err := repo.GetByID(l.ID)
+			switch {
+			case tc.method == http.MethodDelete && tc.status == http.StatusNoContent:
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("Expected link to be deleted, got %v", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case tc.method == http.MethodPatch && tc.status == http.StatusOK:
+				if stored.Url != "https://new.ru" {
+					t.Errorf("Expected url to be updated, got %s", stored.Url)
+				}
+			default:
+				if stored.Url != "https://a.ru" {
+					t.Errorf("Expected url to stay, got %s", stored.Url)
+				}
+			}
+		})
+	}
+}
+
+func TestGoTo(t *testing.T) {
+	repo := newTestRepository(t)
+	router := newTestRouter(repo)
+	createLink(t, repo, "https://example.com/page", "aaaaaa", 1)
+	deleted := createLink(t, repo, "https://deleted.ru", "dddddd", 1)
+	if err := repo.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		status   int
+		location string
+	}{
+		{name: "Found", path: "/aaaaaa", status: http.StatusTemporaryRedirect, location: "https://example.com/page"},
+		{name: "Unknown", path: "/zzzzzz", status: http.StatusNotFound},
+		{name: "Deleted", path: "/dddddd", status: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, tc.path, 0, nil)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if location := w.Header().Get("Location"); location != tc.location {
+				t.Errorf("Expected location %q, got %q", tc.location, location)
+			}
+		})
+	}
+}
+
+func TestGoToPublishesVisit(t *testing.T) {
+	repo := newTestRepository(t)
+	l := createLink(t, repo, "https://example.com", "aaaaaa", 1)
+	bus := event.NewBus(10)
+	visits := make(chan link.LinkVisited, 10)
+	event.Subscribe(bus, link.TopicVisited, "test", func(visit link.LinkVisited) {
+		visits <- visit
+	})
+	router := http.NewServeMux()
+	link.NewHelloHandler(router, link.LinkHandlerDeps{
+		Config:         &configs.Config{},
+		LinkRepository: repo,
+		EventBus:       bus,
+	})
+
+	serve(router, http.MethodGet, "/aaaaaa", 0, nil)
+	serve(router, http.MethodGet, "/zzzzzz", 0, nil)
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(visits) != 1 {
+		t.Fatalf("Expected one visit event, got %d", len(visits))
+	}
+	if visit := <-visits; visit.LinkId != l.ID || visit.VisitedAt.IsZero() {
+		t.Errorf("Unexpected visit %+v", visit)
+	}
+}
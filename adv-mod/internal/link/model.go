@@ -0,0 +1,39 @@
+package link
+
+import (
+	"crypto/rand"
+
+	"gorm.io/gorm"
+)
+
+// HashLength - длина короткого кода. 62^6 ≈ 5.7e10 вариантов, коллизии редки,
+// а оставшиеся ловит уникальный индекс
+const HashLength = 6
+
+const hashAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+type Link struct {
+	gorm.Model
+	Url    string `gorm:"not null"`
+	Hash   string `gorm:"uniqueIndex;not null"`
+	UserId uint   `gorm:"index;not null"`
+}
+
+func NewLink(url string, userId uint) *Link {
+	return &Link{
+		Url:    url,
+		UserId: userId,
+	}
+}
+
+// GenerateHash возвращает случайный код из букв и цифр. Берётся crypto/rand,
+// чтобы по одной ссылке нельзя было угадать соседние
+func GenerateHash() string {
+	b := make([]byte, HashLength)
+	rand.Read(b)
+	for i := range b {
+		// 256 не делится на 62, но небольшой перекос распределения здесь не важен
+		b[i] = hashAlphabet[int(b[i])%len(hashAlphabet)]
+	}
+	return string(b)
+}
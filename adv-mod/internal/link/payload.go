@@ -0,0 +1,48 @@
+package link
+
+import (
+	"adv-mod/pkg/event"
+	"time"
+)
+
+// TopicVisited публикуется при каждом редиректе
+var TopicVisited = event.NewTopic[LinkVisited]("link.visited")
+
+type LinkVisited struct {
+	LinkId    uint
+	VisitedAt time.Time
+}
+
+type LinkCreateRequest struct {
+	Url string `json:"url" validate:"required,http_url"`
+}
+
+type LinkUpdateRequest struct {
+	Url string `json:"url" validate:"required,http_url"`
+}
+
+type LinkResponse struct {
+	Id        uint      `json:"id"`
+	Url       string    `json:"url"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ListLinksResponse struct {
+	Items      []LinkResponse `json:"items"`
+	Total      *int64         `json:"total,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset,omitempty"`
+}
+
+func NewLinkResponse(l *Link) LinkResponse {
+	return LinkResponse{
+		Id:        l.ID,
+		Url:       l.Url,
+		Hash:      l.Hash,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+	}
+}
@@ -0,0 +1,76 @@
+package link
+
+import (
+	"adv-mod/pkg/db"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// MaxHashAttempts - сколько раз CreateWithHash пробует новый код при коллизии
+const MaxHashAttempts = 5
+
+var ErrHashCollision = errors.New("could not generate a unique hash")
+
+type LinkRepository struct {
+	*db.Repository[Link]
+}
+
+func NewLinkRepository(database *db.Db) *LinkRepository {
+	return &LinkRepository{
+		Repository: db.NewRepository[Link](database, db.ListOptions{
+			Filters: map[string]string{
+				"url":  "url",
+				"hash": "hash",
+			},
+			Sorts: map[string]string{
+				"id":         "id",
+				"created_at": "created_at",
+				"url":        "url",
+			},
+			DefaultSort: "-id",
+		}),
+	}
+}
+
+// CreateWithHash подбирает свободный код. Проверка заранее не спасает от гонки,
+// поэтому вставку отклоняет уникальный индекс, и код генерируется заново.
+// Индекс учитывает и мягко удалённые ссылки, так что их коды не переиспользуются
+func (repo *LinkRepository) CreateWithHash(link *Link, generate func() string) (*Link, error) {
+	for range MaxHashAttempts {
+		link.Hash = generate()
+		created, err := repo.Create(link)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			link.ID = 0
+			continue
+		}
+		return created, err
+	}
+	return nil, ErrHashCollision
+}
+
+func (repo *LinkRepository) FindByHash(hash string) (*Link, error) {
+	var link Link
+	result := repo.Database.DB.First(&link, "hash = ?", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &link, nil
+}
+
+// FindOwned находит ссылку пользователя. Чужая ссылка неотличима от отсутствующей
+func (repo *LinkRepository) FindOwned(id, userId uint) (*Link, error) {
+	var link Link
+	result := repo.Database.DB.First(&link, "id = ? AND user_id = ?", id, userId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &link, nil
+}
+
+// OwnedBy ограничивает List ссылками пользователя
+func OwnedBy(userId uint) db.Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ?", userId)
+	}
+}
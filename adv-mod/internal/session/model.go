@@ -0,0 +1,19 @@
+package session
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken - одно звено цепочки ротации. Все токены одного входа
+// делят FamilyId, чтобы при повторном использовании отозвать их разом
+type RefreshToken struct {
+	gorm.Model
+	UserId    uint   `gorm:"index"`
+	FamilyId  string `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"adv-mod/pkg/db"
+	"time"
+)
+
+type RefreshTokenRepository struct {
+	Database *db.Db
+}
+
+func NewRefreshTokenRepository(database *db.Db) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Database: database,
+	}
+}
+
+func (repo *RefreshTokenRepository) Create(token *RefreshToken) (*RefreshToken, error) {
+	result := repo.Database.DB.Create(token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+func (repo *RefreshTokenRepository) FindByHash(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	result := repo.Database.DB.First(&token, "token_hash = ?", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkRotated помечает токен использованным. false означает, что его уже
+// использовали или отозвали - например, параллельным запросом
+func (repo *RefreshTokenRepository) MarkRotated(id uint) (bool, error) {
+	result := repo.Database.DB.Model(&RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (repo *RefreshTokenRepository) RevokeFamily(familyId string) error {
+	result := repo.Database.DB.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now())
+	return result.Error
+}
+
+func (repo *RefreshTokenRepository) RevokeUser(userId uint) error {
+	result := repo.Database.DB.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now())
+	return result.Error
+}
@@ -0,0 +1,102 @@
+package stat
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/openapi"
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/response"
+	"net/http"
+	"time"
+)
+
+const dateLayout = time.DateOnly
+
+type StatHandlerDeps struct {
+	*configs.Config
+	StatRepository *StatRepository
+	OpenAPI        *openapi.Registry
+}
+
+type StatHandler struct {
+	*configs.Config
+	StatRepository *StatRepository
+}
+
+// NewHelloHandler регистрирует статистику переходов по ссылкам текущего пользователя
+func NewHelloHandler(router *http.ServeMux, deps StatHandlerDeps) {
+	handler := &StatHandler{
+		Config:         deps.Config,
+		StatRepository: deps.StatRepository,
+	}
+	failure := response.ErrorBody{}
+	deps.OpenAPI.Handle(router, "GET /stat", middleware.IsAuthed(middleware.RequireScope(rbac.PermissionStatRead)(handler.GetStat()), deps.Config), openapi.Operation{
+		Summary:     "Clicks on links of the current user",
+		Description: "Counts are aggregated asynchronously, recent clicks show up within a few seconds.",
+		Tags:        []string{"stat"},
+		Query: []openapi.Parameter{
+			{Name: "from", Description: "first day, YYYY-MM-DD", Required: true},
+			{Name: "to", Description: "last day inclusive, YYYY-MM-DD", Required: true},
+			{Name: "by", Description: "day (default) or month"},
+		},
+		Responses: map[int]any{200: GetStatResponse{}, 400: failure, 401: failure, 403: failure},
+		Security:  []string{openapi.SecurityBearer, openapi.SecurityAPIKey},
+	})
+}
+
+func (handler *StatHandler) GetStat() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.RequireUserId(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		from, err := time.Parse(dateLayout, query.Get("from"))
+		if err != nil {
+			response.BadRequest(w, "from must be a date YYYY-MM-DD")
+			return
+		}
+		to, err := time.Parse(dateLayout, query.Get("to"))
+		if err != nil {
+			response.BadRequest(w, "to must be a date YYYY-MM-DD")
+			return
+		}
+		if to.Before(from) {
+			response.BadRequest(w, "to must not be before from")
+			return
+		}
+		by := query.Get("by")
+		if by == "" {
+			by = GroupByDay
+		}
+		if by != GroupByDay && by != GroupByMonth {
+			response.BadRequest(w, "by must be day or month")
+			return
+		}
+		days, err := handler.StatRepository.ClicksByDay(userId, from, to)
+		if err != nil {
+			response.InternalServerError(w, err)
+			return
+		}
+		response.Json(w, GetStatResponse{By: by, Items: group(days, by)}, http.StatusOK)
+	}
+}
+
+// group складывает дни в месяцы. Дни приходят отсортированными, поэтому
+// достаточно сравнивать с последним периодом
+func group(days []DayClicks, by string) []StatItem {
+	layout := dateLayout
+	if by == GroupByMonth {
+		layout = "2006-01"
+	}
+	items := make([]StatItem, 0, len(days))
+	for _, day := range days {
+		period := day.Date.UTC().Format(layout)
+		if n := len(items); n > 0 && items[n-1].Period == period {
+			items[n-1].Clicks += day.Clicks
+			continue
+		}
+		items = append(items, StatItem{Period: period, Clicks: day.Clicks})
+	}
+	return items
+}
@@ -0,0 +1,110 @@
+package stat_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/internal/link"
+	"adv-mod/internal/stat"
+	"adv-mod/pkg/jwt"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testSecret = "secret"
+
+func TestGetStat(t *testing.T) {
+	database := newTestDb(t)
+	links := link.NewLinkRepository(database)
+	own, _ := links.Create(&link.Link{Url: "https://a.ru", Hash: "aaaaaa", UserId: 1})
+	other, _ := links.Create(&link.Link{Url: "https://b.ru", Hash: "bbbbbb", UserId: 2})
+	deleted, _ := links.Create(&link.Link{Url: "https://c.ru", Hash: "cccccc", UserId: 1})
+	links.Delete(deleted.ID)
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC) }
+	err := stat.NewStatRepository(database).AddClicks(map[stat.Key]int{
+		stat.NewKey(own.ID, day(9, 30)):     1,
+		stat.NewKey(own.ID, day(10, 1)):     2,
+		stat.NewKey(deleted.ID, day(10, 1)): 3,
+		stat.NewKey(own.ID, day(10, 5)):     4,
+		stat.NewKey(other.ID, day(10, 5)):   100,
+		stat.NewKey(own.ID, day(11, 1)):     8,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := http.NewServeMux()
+	stat.NewHelloHandler(router, stat.StatHandlerDeps{
+		Config:         &configs.Config{Auth: configs.AuthConfig{Secret: testSecret}},
+		StatRepository: stat.NewStatRepository(database),
+	})
+	token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "a@a.ru", UserId: 1})
+
+	testCases := []struct {
+		name   string
+		query  string
+		status int
+		items  []stat.StatItem
+	}{
+		{
+			name:   "By day",
+			query:  "from=2026-09-30&to=2026-10-31&by=day",
+			status: http.StatusOK,
+			items:  []stat.StatItem{{Period: "2026-09-30", Clicks: 1}, {Period: "2026-10-01", Clicks: 5}, {Period: "2026-10-05", Clicks: 4}},
+		},
+		{
+			name:   "By month",
+			query:  "from=2026-09-01&to=2026-11-30&by=month",
+			status: http.StatusOK,
+			items:  []stat.StatItem{{Period: "2026-09", Clicks: 1}, {Period: "2026-10", Clicks: 9}, {Period: "2026-11", Clicks: 8}},
+		},
+		{
+			name:   "Day by default",
+			query:  "from=2026-10-05&to=2026-10-05",
+			status: http.StatusOK,
+			items:  []stat.StatItem{{Period: "2026-10-05", Clicks: 4}},
+		},
+		{name: "Empty range", query: "from=2025-01-01&to=2025-01-31", status: http.StatusOK, items: []stat.StatItem{}},
+		{name: "Missing from", query: "to=2026-10-05", status: http.StatusBadRequest},
+		{name: "Invalid to", query: "from=2026-10-05&to=yesterday", status: http.StatusBadRequest},
+		{name: "Reversed range", query: "from=2026-10-05&to=2026-10-01", status: http.StatusBadRequest},
+		{name: "Unknown grouping", query: "from=2026-10-01&to=2026-10-05&by=week", status: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stat?"+tc.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("Expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			var resp stat.GetStatResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(resp.Items, tc.items) {
+				t.Errorf("Expected %v, got %v", tc.items, resp.Items)
+			}
+		})
+	}
+}
+
+func TestGetStatRequiresToken(t *testing.T) {
+	router := http.NewServeMux()
+	stat.NewHelloHandler(router, stat.StatHandlerDeps{
+		Config:         &configs.Config{Auth: configs.AuthConfig{Secret: testSecret}},
+		StatRepository: stat.NewStatRepository(newTestDb(t)),
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stat?from=2026-10-01&to=2026-10-05", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,29 @@
+package stat
+
+import "time"
+
+// Stat - число переходов по ссылке за сутки UTC. Строку на пару (link_id, date)
+// только увеличивает воркер, поэтому мягкого удаления у модели нет
+type Stat struct {
+	ID        uint      `gorm:"primarykey"`
+	LinkId    uint      `gorm:"not null;uniqueIndex:idx_stats_link_date"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex:idx_stats_link_date"`
+	Clicks    int       `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// Key - ячейка агрегации: ссылка и день
+type Key struct {
+	LinkId uint
+	Date   time.Time
+}
+
+func NewKey(linkId uint, at time.Time) Key {
+	return Key{LinkId: linkId, Date: Day(at)}
+}
+
+// Day обрезает время до начала суток UTC
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,17 @@
+package stat
+
+const (
+	GroupByDay   = "day"
+	GroupByMonth = "month"
+)
+
+type StatItem struct {
+	// Period - "2006-01-02" для by=day и "2006-01" для by=month
+	Period string `json:"period"`
+	Clicks int    `json:"clicks"`
+}
+
+type GetStatResponse struct {
+	By    string     `json:"by"`
+	Items []StatItem `json:"items"`
+}
@@ -0,0 +1,64 @@
+package stat
+
+import (
+	"adv-mod/pkg/db"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatRepository struct {
+	Database *db.Db
+}
+
+func NewStatRepository(database *db.Db) *StatRepository {
+	return &StatRepository{
+		Database: database,
+	}
+}
+
+// AddClicks прибавляет накопленные переходы одной транзакцией. Upsert по
+// (link_id, date) не теряет клики, даже если строку параллельно создал другой процесс
+func (repo *StatRepository) AddClicks(clicks map[Key]int) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	return repo.Database.Transaction(func(tx *gorm.DB) error {
+		for key, count := range clicks {
+			result := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "link_id"}, {Name: "date"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"clicks":     gorm.Expr("stats.clicks + ?", count),
+					"updated_at": time.Now(),
+				}),
+			}).Create(&Stat{LinkId: key.LinkId, Date: key.Date, Clicks: count})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
+
+type DayClicks struct {
+	Date   time.Time
+	Clicks int
+}
+
+// ClicksByDay суммирует переходы по всем ссылкам пользователя за каждый день
+// отрезка [from, to]. Клики удалённых ссылок остаются в истории
+func (repo *StatRepository) ClicksByDay(userId uint, from, to time.Time) ([]DayClicks, error) {
+	var days []DayClicks
+	result := repo.Database.DB.Table("stats").
+		Select("stats.date AS date, SUM(stats.clicks) AS clicks").
+		Joins("JOIN links ON links.id = stats.link_id").
+		Where("links.user_id = ? AND stats.date BETWEEN ? AND ?", userId, Day(from), Day(to)).
+		Group("stats.date").
+		Order("stats.date").
+		Scan(&days)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return days, nil
+}
@@ -0,0 +1,124 @@
+package stat
+
+import (
+	"adv-mod/internal/link"
+	"adv-mod/pkg/event"
+	"adv-mod/pkg/metrics"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 5 * time.Second
+	DefaultMaxFailures   = 5
+)
+
+type StatWorkerDeps struct {
+	StatRepository *StatRepository
+	EventBus       *event.Bus
+	BatchSize      int
+	FlushInterval  time.Duration
+	// MaxFailures - сколько неудачных сохранений подряд клики ждут в памяти,
+	// прежде чем их выбросят
+	MaxFailures int
+	// Registry необязателен. С ним выброшенные клики считаются в метрике
+	// stat_clicks_dropped_total
+	Registry *metrics.Registry
+}
+
+// StatWorker копит переходы в памяти и пишет их пачкой: по BatchSize событий
+// или раз в FlushInterval, смотря что наступит раньше
+type StatWorker struct {
+	StatRepository *StatRepository
+	BatchSize      int
+	FlushInterval  time.Duration
+	MaxFailures    int
+	Dropped        *metrics.Counter
+
+	mu       sync.Mutex
+	pending  map[Key]int
+	events   int
+	failures int
+}
+
+// NewStatWorker подписывает воркер на link.TopicVisited
+func NewStatWorker(deps StatWorkerDeps) *StatWorker {
+	worker := &StatWorker{
+		StatRepository: deps.StatRepository,
+		BatchSize:      deps.BatchSize,
+		FlushInterval:  deps.FlushInterval,
+		MaxFailures:    deps.MaxFailures,
+		pending:        map[Key]int{},
+	}
+	if worker.BatchSize <= 0 {
+		worker.BatchSize = DefaultBatchSize
+	}
+	if worker.FlushInterval <= 0 {
+		worker.FlushInterval = DefaultFlushInterval
+	}
+	if worker.MaxFailures <= 0 {
+		worker.MaxFailures = DefaultMaxFailures
+	}
+	if deps.Registry != nil {
+		worker.Dropped = deps.Registry.NewCounter("stat_clicks_dropped_total", "Clicks dropped after repeated failures to save them.")
+	}
+	event.Subscribe(deps.EventBus, link.TopicVisited, "stat", worker.AddVisit)
+	return worker
+}
+
+// Run сбрасывает накопленное по таймеру и последний раз при отмене ctx.
+// Шину нужно остановить раньше, иначе события после выхода Run не сохранятся
+func (worker *StatWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			worker.Flush()
+		case <-ctx.Done():
+			worker.Flush()
+			return
+		}
+	}
+}
+
+func (worker *StatWorker) AddVisit(visit link.LinkVisited) {
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+	worker.pending[NewKey(visit.LinkId, visit.VisitedAt)]++
+	worker.events++
+	// После ошибки повторяем только по таймеру, а не на каждом переходе
+	if worker.events >= worker.BatchSize && worker.failures == 0 {
+		worker.flush()
+	}
+}
+
+func (worker *StatWorker) Flush() {
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+	worker.flush()
+}
+
+// flush при ошибке оставляет клики в памяти до следующего тика. После
+// MaxFailures ошибок подряд они выбрасываются, чтобы память не росла без предела
+func (worker *StatWorker) flush() {
+	if len(worker.pending) == 0 {
+		return
+	}
+	err := worker.StatRepository.AddClicks(worker.pending)
+	if err != nil {
+		worker.failures++
+		if worker.failures < worker.MaxFailures {
+			slog.Error("save click stats", "error", err, "events", worker.events, "failures", worker.failures)
+			return
+		}
+		slog.Error("drop click stats", "error", err, "events", worker.events, "failures", worker.failures)
+		worker.Dropped.Add(float64(worker.events))
+	}
+	worker.pending = map[Key]int{}
+	worker.events = 0
+	worker.failures = 0
+}
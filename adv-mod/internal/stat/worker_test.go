@@ -0,0 +1,207 @@
+package stat_test
+
+import (
+	"adv-mod/internal/dbtest"
+	"adv-mod/internal/link"
+	"adv-mod/internal/stat"
+	"adv-mod/pkg/db"
+	"adv-mod/pkg/event"
+	"adv-mod/pkg/metrics"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+var today = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestDb(t *testing.T) *db.Db {
+	t.Helper()
+	return dbtest.Open(t, &link.Link{}, &stat.Stat{})
+}
+
+func visit(bus *event.Bus, linkId uint, at time.Time) {
+	event.Publish(bus, link.TopicVisited, link.LinkVisited{LinkId: linkId, VisitedAt: at})
+}
+
+func clicks(t *testing.T, database *db.Db) map[stat.Key]int {
+	t.Helper()
+	var stats []stat.Stat
+	if err := database.Find(&stats).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := map[stat.Key]int{}
+	for _, s := range stats {
+		result[stat.NewKey(s.LinkId, s.Date)] = s.Clicks
+	}
+	return result
+}
+
+// startWorker возвращает функцию остановки, которая ждёт выхода Run
+func startWorker(worker *stat.StatWorker) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition was not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAddClicksAccumulates(t *testing.T) {
+	database := newTestDb(t)
+	repo := stat.NewStatRepository(database)
+	key := stat.NewKey(1, today)
+	for range 2 {
+		if err := repo.AddClicks(map[stat.Key]int{key: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := clicks(t, database)[key]; got != 6 {
+		t.Errorf("Expected 6 clicks, got %d", got)
+	}
+}
+
+func TestWorkerFlushesBatch(t *testing.T) {
+	database := newTestDb(t)
+	bus := event.NewBus(10)
+	stop := startWorker(stat.NewStatWorker(stat.StatWorkerDeps{
+		StatRepository: stat.NewStatRepository(database),
+		EventBus:       bus,
+		BatchSize:      3,
+		FlushInterval:  time.Hour,
+	}))
+	defer stop()
+
+	visit(bus, 1, today)
+	visit(bus, 1, today.Add(time.Hour))
+	time.Sleep(50 * time.Millisecond)
+	if got := clicks(t, database); len(got) != 0 {
+		t.Fatalf("Expected nothing before the batch is full, got %v", got)
+	}
+
+	visit(bus, 2, today.AddDate(0, 0, -1))
+	waitFor(t, func() bool { return len(clicks(t, database)) == 2 })
+	got := clicks(t, database)
+	if got[stat.NewKey(1, today)] != 2 || got[stat.NewKey(2, today.AddDate(0, 0, -1))] != 1 {
+		t.Errorf("Unexpected clicks %v", got)
+	}
+}
+
+func TestWorkerFlushesOnInterval(t *testing.T) {
+	database := newTestDb(t)
+	bus := event.NewBus(10)
+	stop := startWorker(stat.NewStatWorker(stat.StatWorkerDeps{
+		StatRepository: stat.NewStatRepository(database),
+		EventBus:       bus,
+		BatchSize:      100,
+		FlushInterval:  20 * time.Millisecond,
+	}))
+	defer stop()
+
+	visit(bus, 1, today)
+	waitFor(t, func() bool { return clicks(t, database)[stat.NewKey(1, today)] == 1 })
+}
+
+func TestWorkerFlushesOnShutdown(t *testing.T) {
+	database := newTestDb(t)
+	bus := event.NewBus(10)
+	stop := startWorker(stat.NewStatWorker(stat.StatWorkerDeps{
+		StatRepository: stat.NewStatRepository(database),
+		EventBus:       bus,
+		BatchSize:      100,
+		FlushInterval:  time.Hour,
+	}))
+	// Пачка не набрана и таймер не сработал: клики есть только в памяти и очереди шины
+	for range 5 {
+		visit(bus, 1, today)
+	}
+
+	// Порядок как в run: сначала шина разбирает очереди, потом воркер сохраняет остаток
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := bus.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if got := clicks(t, database)[stat.NewKey(1, today)]; got != 5 {
+		t.Errorf("Expected pending events to be saved, got %d", got)
+	}
+}
+
+func TestWorkerRetriesFailedFlush(t *testing.T) {
+	database := newTestDb(t)
+	worker := stat.NewStatWorker(stat.StatWorkerDeps{
+		StatRepository: stat.NewStatRepository(database),
+		EventBus:       event.NewBus(10),
+		BatchSize:      2,
+		FlushInterval:  time.Hour,
+	})
+	if err := database.Migrator().DropTable(&stat.Stat{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пачка набрана, но сохранить её не удалось
+	worker.AddVisit(link.LinkVisited{LinkId: 1, VisitedAt: today})
+	worker.AddVisit(link.LinkVisited{LinkId: 1, VisitedAt: today})
+	worker.AddVisit(link.LinkVisited{LinkId: 1, VisitedAt: today})
+
+	if err := database.AutoMigrate(&stat.Stat{}); err != nil {
+		t.Fatal(err)
+	}
+	worker.Flush()
+	if got := clicks(t, database)[stat.NewKey(1, today)]; got != 3 {
+		t.Errorf("Expected 3 clicks after retry, got %d", got)
+	}
+}
+
+func TestWorkerDropsAfterMaxFailures(t *testing.T) {
+	database := newTestDb(t)
+	registry := metrics.NewRegistry()
+	worker := stat.NewStatWorker(stat.StatWorkerDeps{
+		StatRepository: stat.NewStatRepository(database),
+		EventBus:       event.NewBus(10),
+		BatchSize:      1,
+		FlushInterval:  time.Hour,
+		MaxFailures:    2,
+		Registry:       registry,
+	})
+	if err := database.Migrator().DropTable(&stat.Stat{}); err != nil {
+		t.Fatal(err)
+	}
+
+	worker.AddVisit(link.LinkVisited{LinkId: 1, VisitedAt: today})
+	worker.AddVisit(link.LinkVisited{LinkId: 1, VisitedAt: today})
+	worker.Flush()
+
+	var out bytes.Buffer
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "stat_clicks_dropped_total 2") {
+		t.Errorf("Expected 2 dropped clicks, got:\n%s", out.String())
+	}
+
+	if err := database.AutoMigrate(&stat.Stat{}); err != nil {
+		t.Fatal(err)
+	}
+	worker.AddVisit(link.LinkVisited{LinkId: 1, VisitedAt: today})
+	if got := clicks(t, database)[stat.NewKey(1, today)]; got != 1 {
+		t.Errorf("Expected only the new click to be saved, got %d", got)
+	}
+}
@@ -0,0 +1,17 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	gorm.Model
+	// Уникален среди неудалённых: адрес удалённого аккаунта можно занять снова
+	Email           string `gorm:"uniqueIndex:idx_users_email,where:deleted_at IS NULL"`
+	Password        string
+	Name            string
+	Role            string `gorm:"not null;default:user"`
+	EmailVerifiedAt *time.Time
+}
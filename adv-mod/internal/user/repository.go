@@ -0,0 +1,127 @@
+package user
+
+import (
+	"adv-mod/internal/verification"
+	"adv-mod/pkg/db"
+	"adv-mod/pkg/rbac"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	Database *db.Db
+}
+
+func NewUserRepository(database *db.Db) *UserRepository {
+	return &UserRepository{
+		Database: database,
+	}
+}
+
+func (repo *UserRepository) Create(user *User) (*User, error) {
+	result := repo.Database.DB.Create(user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return user, nil
+}
+
+func (repo *UserRepository) FindByEmail(email string) (*User, error) {
+	var user User
+	result := repo.Database.DB.First(&user, "email = ?", email)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (repo *UserRepository) FindById(id uint) (*User, error) {
+	var user User
+	result := repo.Database.DB.First(&user, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (repo *UserRepository) Update(user *User) (*User, error) {
+	result := repo.Database.DB.Updates(user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return user, nil
+}
+
+// List возвращает страницу пользователей по возрастанию id и общее количество
+func (repo *UserRepository) List(limit, offset int) ([]User, int64, error) {
+	var total int64
+	result := repo.Database.DB.Model(&User{}).Count(&total)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	var users []User
+	result = repo.Database.DB.Order("id").Limit(limit).Offset(offset).Find(&users)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return users, total, nil
+}
+
+func (repo *UserRepository) UpdateRole(id uint, role string) error {
+	result := repo.Database.DB.Model(&User{}).Where("id = ?", id).Update("role", role)
+	return result.Error
+}
+
+// MarkEmailVerified подтверждает email, только если он не сменился с момента,
+// когда его прочитал вызывающий. false означает, что адрес уже другой
+func (repo *UserRepository) MarkEmailVerified(id uint, email string) (bool, error) {
+	result := repo.Database.DB.Model(&User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SetPassword меняет только хэш пароля, не трогая остальные поля
+func (repo *UserRepository) SetPassword(id uint, hashedPassword string) error {
+	result := repo.Database.DB.Model(&User{}).Where("id = ?", id).Update("password", hashedPassword)
+	return result.Error
+}
+
+// PromoteAdmins выдаёт роль admin пользователям с подтверждённым email из списка
+func (repo *UserRepository) PromoteAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	result := repo.Database.DB.Model(&User{}).
+		Where("email IN ? AND email_verified_at IS NOT NULL", emails).
+		Update("role", rbac.RoleAdmin)
+	return result.Error
+}
+
+// ChangeEmail меняет email и сбрасывает подтверждение: новый адрес нужно подтвердить заново.
+// В той же транзакции гасит все ссылки из писем, отправленных на прежний адрес
+func (repo *UserRepository) ChangeEmail(id uint, email string) error {
+	return repo.Database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+			"email":             email,
+			"email_verified_at": nil,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Model(&verification.Token{}).
+			Where("user_id = ? AND used_at IS NULL", id).
+			Update("used_at", time.Now())
+		return result.Error
+	})
+}
+
+// Delete помечает пользователя удалённым, запись остаётся в таблице
+func (repo *UserRepository) Delete(id uint) error {
+	result := repo.Database.DB.Delete(&User{}, id)
+	return result.Error
+}
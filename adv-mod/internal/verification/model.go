@@ -0,0 +1,24 @@
+package verification
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// Token - одноразовый токен из письма. Хранится только хэш
+type Token struct {
+	gorm.Model
+	UserId    uint   `gorm:"index"`
+	Purpose   string `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	// Email - адрес, на который ушло письмо. Токен действует, пока он совпадает с email пользователя
+	Email string
+}
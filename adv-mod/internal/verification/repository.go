@@ -0,0 +1,53 @@
+package verification
+
+import (
+	"adv-mod/pkg/db"
+	"time"
+)
+
+type TokenRepository struct {
+	Database *db.Db
+}
+
+func NewTokenRepository(database *db.Db) *TokenRepository {
+	return &TokenRepository{
+		Database: database,
+	}
+}
+
+func (repo *TokenRepository) Create(token *Token) (*Token, error) {
+	result := repo.Database.DB.Create(token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+func (repo *TokenRepository) FindByHash(hash, purpose string) (*Token, error) {
+	var token Token
+	result := repo.Database.DB.First(&token, "token_hash = ? AND purpose = ?", hash, purpose)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkUsed гасит токен. false означает, что его уже использовали
+func (repo *TokenRepository) MarkUsed(id uint) (bool, error) {
+	result := repo.Database.DB.Model(&Token{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateUser гасит все неиспользованные токены пользователя с этим назначением,
+// чтобы работала только последняя отправленная ссылка
+func (repo *TokenRepository) InvalidateUser(userId uint, purpose string) error {
+	result := repo.Database.DB.Model(&Token{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose).
+		Update("used_at", time.Now())
+	return result.Error
+}
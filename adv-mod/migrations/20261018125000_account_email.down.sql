@@ -0,0 +1,5 @@
+ALTER TABLE tokens DROP COLUMN IF EXISTS email;
+
+-- Упадёт, если email удалённого пользователя уже занят заново
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
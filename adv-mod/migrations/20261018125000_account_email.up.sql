@@ -0,0 +1,6 @@
+-- Мягко удалённый пользователь не должен занимать email навсегда
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+
+-- Адрес, на который ушла ссылка из письма. После смены email ссылка недействительна
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS email TEXT;
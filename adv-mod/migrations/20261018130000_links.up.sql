@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS links (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    url TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    user_id BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_links_deleted_at ON links (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_links_hash ON links (hash);
+CREATE INDEX IF NOT EXISTS idx_links_user_id ON links (user_id);
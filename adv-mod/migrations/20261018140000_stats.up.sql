@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS stats (
+    id BIGSERIAL PRIMARY KEY,
+    link_id BIGINT NOT NULL,
+    date DATE NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stats_link_date ON stats (link_id, date);
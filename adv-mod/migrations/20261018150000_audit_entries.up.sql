@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    action TEXT NOT NULL,
+    actor_id BIGINT,
+    actor_email TEXT,
+    target_id BIGINT,
+    ip TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    details TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_created_at ON audit_entries (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_action ON audit_entries (action);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_actor_id ON audit_entries (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_actor_email ON audit_entries (actor_email);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_request_id ON audit_entries (request_id);
+
+-- Журнал только пополняется: изменить или удалить запись нельзя даже напрямую через SQL
+CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_entries_append_only ON audit_entries;
+CREATE TRIGGER audit_entries_append_only
+    BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only();
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS - SQL миграции, вшитые в бинарник. Новые создаются командой `migrate create <name>`
+//
+//go:embed *.sql
+var FS embed.FS
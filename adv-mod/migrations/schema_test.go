@@ -0,0 +1,307 @@
+package migrations_test
+
+import (
+	"adv-mod/internal/apikey"
+	"adv-mod/internal/audit"
+	"adv-mod/internal/link"
+	"adv-mod/internal/session"
+	"adv-mod/internal/stat"
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+	"adv-mod/migrations"
+	"adv-mod/pkg/db"
+	"context"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// models - всё, что приложение хранит в БД. Тесты пакетов строят схему через
+// AutoMigrate из этих моделей, поэтому SQL миграции должны описывать ту же схему
+var models = []any{
+	&user.User{},
+	&session.RefreshToken{},
+	&verification.Token{},
+	&link.Link{},
+	&stat.Stat{},
+	&audit.Entry{},
+	&apikey.ApiKey{},
+}
+
+type column struct {
+	kind    string
+	notNull bool
+}
+
+type index struct {
+	unique  bool
+	columns []string
+	where   string
+}
+
+type table struct {
+	columns map[string]column
+	indexes map[string]index
+}
+
+var (
+	blockComment  = regexp.MustCompile(`(?s)\$\$.*?\$\$`)
+	lineComment   = regexp.MustCompile(`--[^\n]*`)
+	spaces        = regexp.MustCompile(`\s+`)
+	createTable   = regexp.MustCompile(`(?is)^CREATE TABLE (?:IF NOT EXISTS )?(\w+) \((.*)\)$`)
+	createIndex   = regexp.MustCompile(`(?is)^CREATE (UNIQUE )?INDEX (?:IF NOT EXISTS )?(\w+) ON (\w+) \(([^)]*)\)(?: WHERE (.*))?$`)
+	dropIndex     = regexp.MustCompile(`(?is)^DROP INDEX (?:IF EXISTS )?(\w+)$`)
+	addColumn     = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) ADD COLUMN (?:IF NOT EXISTS )?(\w+ .*)$`)
+	ignoredPrefix = []string{"CREATE OR REPLACE FUNCTION", "CREATE TRIGGER", "DROP TRIGGER"}
+)
+
+// sqlKind сводит тип Postgres к семейству, которое можно сравнить с типом поля модели
+func sqlKind(sqlType string) string {
+	switch strings.ToUpper(sqlType) {
+	case "BIGSERIAL", "SERIAL", "BIGINT", "INTEGER", "SMALLINT":
+		return "int"
+	case "TEXT", "VARCHAR":
+		return "string"
+	case "TIMESTAMPTZ", "TIMESTAMP":
+		return "time"
+	case "DATE":
+		return "date"
+	case "BOOLEAN":
+		return "bool"
+	}
+	return sqlType
+}
+
+func fieldKind(field *schema.Field) string {
+	if strings.EqualFold(field.TagSettings["TYPE"], "date") {
+		return "date"
+	}
+	if field.Serializer != nil {
+		return "string"
+	}
+	switch field.DataType {
+	case schema.Int, schema.Uint:
+		return "int"
+	case schema.String:
+		return "string"
+	case schema.Time:
+		return "time"
+	case schema.Bool:
+		return "bool"
+	}
+	return string(field.DataType)
+}
+
+func normalizeWhere(where string) string {
+	return strings.ToLower(spaces.ReplaceAllString(strings.TrimSpace(where), " "))
+}
+
+// addDefinition разбирает определение колонки вида "name TYPE [NOT NULL] ..."
+func addDefinition(target *table, definition string) {
+	parts := strings.Fields(definition)
+	upper := strings.ToUpper(definition)
+	target.columns[parts[0]] = column{
+		kind:    sqlKind(parts[1]),
+		notNull: strings.Contains(upper, "NOT NULL") || strings.Contains(upper, "PRIMARY KEY"),
+	}
+}
+
+// migratedSchema применяет up миграции по порядку к описанию схемы. Незнакомая
+// инструкция роняет тест, чтобы расхождение не прошло незамеченным
+func migratedSchema(t *testing.T) map[string]*table {
+	t.Helper()
+	loaded, err := db.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]*table{}
+	for _, migration := range loaded {
+		body := blockComment.ReplaceAllString(lineComment.ReplaceAllString(migration.Up, ""), "$$$$")
+		for statement := range strings.SplitSeq(body, ";") {
+			statement = spaces.ReplaceAllString(strings.TrimSpace(statement), " ")
+			if statement == "" || slices.ContainsFunc(ignoredPrefix, func(prefix string) bool {
+				return strings.HasPrefix(strings.ToUpper(statement), prefix)
+			}) {
+				continue
+			}
+			if m := createTable.FindStringSubmatch(statement); m != nil {
+				created := &table{columns: map[string]column{}, indexes: map[string]index{}}
+				for definition := range strings.SplitSeq(m[2], ",") {
+					addDefinition(created, definition)
+				}
+				tables[m[1]] = created
+				continue
+			}
+			if m := addColumn.FindStringSubmatch(statement); m != nil {
+				target, ok := tables[m[1]]
+				if !ok {
+					t.Fatalf("%d_%s: column on unknown table %s", migration.Version, migration.Name, m[1])
+				}
+				addDefinition(target, m[2])
+				continue
+			}
+			if m := createIndex.FindStringSubmatch(statement); m != nil {
+				target, ok := tables[m[3]]
+				if !ok {
+					t.Fatalf("%d_%s: index %s on unknown table %s", migration.Version, migration.Name, m[2], m[3])
+				}
+				columns := strings.Split(strings.ReplaceAll(m[4], " ", ""), ",")
+				target.indexes[m[2]] = index{unique: m[1] != "", columns: columns, where: normalizeWhere(m[5])}
+				continue
+			}
+			if m := dropIndex.FindStringSubmatch(statement); m != nil {
+				for _, target := range tables {
+					delete(target.indexes, m[1])
+				}
+				continue
+			}
+			t.Fatalf("%d_%s: unsupported statement %q, teach schema_test about it", migration.Version, migration.Name, statement)
+		}
+	}
+	return tables
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	tables := migratedSchema(t)
+	cache := &sync.Map{}
+	seen := map[string]bool{}
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[s.Table] = true
+		migrated, ok := tables[s.Table]
+		if !ok {
+			t.Errorf("Table %s of %s is not created by migrations", s.Table, s.Name)
+			continue
+		}
+		fields := map[string]bool{}
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			fields[field.DBName] = true
+			col, ok := migrated.columns[field.DBName]
+			if !ok {
+				t.Errorf("Column %s.%s is missing in migrations", s.Table, field.DBName)
+				continue
+			}
+			if kind := fieldKind(field); col.kind != kind {
+				t.Errorf("Expected %s.%s to be %s, got %s", s.Table, field.DBName, kind, col.kind)
+			}
+			if !field.PrimaryKey && field.NotNull != col.notNull {
+				t.Errorf("Expected %s.%s not null %v, got %v", s.Table, field.DBName, field.NotNull, col.notNull)
+			}
+		}
+		for name := range migrated.columns {
+			if !fields[name] {
+				t.Errorf("Column %s.%s is not in model %s", s.Table, name, s.Name)
+			}
+		}
+		modelIndexes := map[string]bool{}
+		for _, idx := range s.ParseIndexes() {
+			modelIndexes[idx.Name] = true
+			want := index{unique: idx.Class == "UNIQUE", where: normalizeWhere(idx.Where)}
+			for _, option := range idx.Fields {
+				want.columns = append(want.columns, option.DBName)
+			}
+			got, ok := migrated.indexes[idx.Name]
+			if !ok {
+				t.Errorf("Index %s is missing in migrations", idx.Name)
+				continue
+			}
+			if got.unique != want.unique || !slices.Equal(got.columns, want.columns) || got.where != want.where {
+				t.Errorf("Index %s differs: migrations %+v, model %+v", idx.Name, got, want)
+			}
+		}
+		for name := range migrated.indexes {
+			if !modelIndexes[name] {
+				t.Errorf("Index %s is not declared in model %s", name, s.Name)
+			}
+		}
+	}
+	for name := range tables {
+		if !seen[name] {
+			t.Errorf("Table %s has no model in schema_test", name)
+		}
+	}
+}
+
+// TestMigrationsOnPostgres применяет настоящие миграции. Нужна пустая база:
+//
+//	TEST_POSTGRES_DSN="host=localhost user=postgres password=admin dbname=adv_test port=5552 sslmode=disable" go test ./migrations
+func TestMigrationsOnPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	gormDb, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := &db.Db{DB: gormDb}
+	t.Cleanup(func() { database.Close() })
+	migrator, err := db.NewMigrator(database, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for range migrator.Migrations {
+			if _, err := migrator.Down(ctx); err != nil {
+				t.Errorf("Down failed: %v", err)
+				return
+			}
+		}
+	})
+
+	for _, model := range models {
+		s := mustParse(t, model)
+		columnTypes, err := gormDb.Migrator().ColumnTypes(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nullable := map[string]bool{}
+		for _, columnType := range columnTypes {
+			nullable[columnType.Name()], _ = columnType.Nullable()
+		}
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			isNullable, ok := nullable[field.DBName]
+			if !ok {
+				t.Errorf("Column %s.%s is missing", s.Table, field.DBName)
+				continue
+			}
+			if !field.PrimaryKey && field.NotNull == isNullable {
+				t.Errorf("Expected %s.%s not null %v", s.Table, field.DBName, field.NotNull)
+			}
+		}
+		for _, idx := range s.ParseIndexes() {
+			if !gormDb.Migrator().HasIndex(model, idx.Name) {
+				t.Errorf("Index %s is missing on %s", idx.Name, s.Table)
+			}
+		}
+	}
+}
+
+func mustParse(t *testing.T, model any) *schema.Schema {
+	t.Helper()
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -2,19 +2,81 @@ package db
 
 import (
 	"adv-mod/configs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// maxConnectBackoff ограничивает паузу между попытками подключения
+const maxConnectBackoff = 30 * time.Second
+
 type Db struct {
 	*gorm.DB
 }
 
-func NewDb(conf *configs.Config) *Db {
-	db, err := gorm.Open(postgres.Open(conf.Db.Dsn), &gorm.Config{})
+// NewDb подключается к Postgres, повторяя попытки, пока база не поднимется
+func NewDb(ctx context.Context, conf *configs.Config) (*Db, error) {
+	return Open(ctx, postgres.Open(conf.Db.Dsn), conf.Db)
+}
+
+// Open настраивает пул и ждёт ответа базы до conf.ConnectAttempts раз,
+// удваивая паузу от conf.ConnectBackoff
+func Open(ctx context.Context, dialector gorm.Dialector, conf configs.DbConfig) (*Db, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError:       true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+
+	attempts := max(conf.ConnectAttempts, 1)
+	backoff := conf.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err = sqlDB.PingContext(ctx)
+		if err == nil {
+			return &Db{db}, nil
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			break
+		}
+		slog.Warn("database is not ready", "attempt", attempt, "retry_in", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+	sqlDB.Close()
+	return nil, fmt.Errorf("connect to database: %w", err)
+}
+
+// Ping проверяет, что база отвечает
+func (db *Db) Ping(ctx context.Context) error {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close закрывает пул соединений, которым владеет gorm
+func (db *Db) Close() error {
+	sqlDB, err := db.DB.DB()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return &Db{db}
+	return sqlDB.Close()
 }
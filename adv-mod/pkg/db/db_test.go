@@ -0,0 +1,68 @@
+package db_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+)
+
+// Порт 1 закрыт, подключение отклоняется сразу
+const unreachableDsn = "host=127.0.0.1 port=1 user=postgres dbname=link sslmode=disable connect_timeout=1"
+
+func TestOpenConfiguresPool(t *testing.T) {
+	conf := configs.Default().Db
+	conf.MaxOpenConns = 3
+	database, err := db.Open(context.Background(), sqlite.Open(":memory:"), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := sqlDB.Stats(); stats.MaxOpenConnections != 3 {
+		t.Errorf("Expected 3 max open connections, got %d", stats.MaxOpenConnections)
+	}
+	if err := database.Ping(context.Background()); err != nil {
+		t.Errorf("Expected ping to succeed, got %v", err)
+	}
+}
+
+func TestOpenRetries(t *testing.T) {
+	conf := configs.DbConfig{
+		ConnectAttempts: 3,
+		ConnectBackoff:  20 * time.Millisecond,
+	}
+	start := time.Now()
+	_, err := db.Open(context.Background(), postgres.Open(unreachableDsn), conf)
+	if err == nil {
+		t.Fatal("Expected connection error")
+	}
+	// Паузы 20ms и 40ms между тремя попытками
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected backoff between attempts, finished in %v", elapsed)
+	}
+}
+
+func TestOpenStopsOnCancel(t *testing.T) {
+	conf := configs.DbConfig{
+		ConnectAttempts: 100,
+		ConnectBackoff:  time.Hour,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := db.Open(ctx, postgres.Open(unreachableDsn), conf)
+	if err == nil {
+		t.Fatal("Expected connection error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to stop retries, took %v", elapsed)
+	}
+}
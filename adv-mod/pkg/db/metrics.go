@@ -0,0 +1,30 @@
+package db
+
+import (
+	"adv-mod/pkg/metrics"
+	"database/sql"
+)
+
+// Stats возвращает состояние пула соединений
+func (db *Db) Stats() sql.DBStats {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
+}
+
+// RegisterMetrics публикует статистику пула, снимаемую при каждом чтении метрик
+func (db *Db) RegisterMetrics(registry *metrics.Registry) {
+	registry.Collect(func(emit func(name, help, kind string, value float64)) {
+		stats := db.Stats()
+		emit("db_max_open_connections", "Maximum number of open connections to the database.", "gauge", float64(stats.MaxOpenConnections))
+		emit("db_open_connections", "Established connections, in use and idle.", "gauge", float64(stats.OpenConnections))
+		emit("db_in_use_connections", "Connections currently in use.", "gauge", float64(stats.InUse))
+		emit("db_idle_connections", "Idle connections.", "gauge", float64(stats.Idle))
+		emit("db_wait_count_total", "Connections waited for.", "counter", float64(stats.WaitCount))
+		emit("db_wait_duration_seconds_total", "Time blocked waiting for a new connection.", "counter", stats.WaitDuration.Seconds())
+		emit("db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.", "counter", float64(stats.MaxIdleClosed))
+		emit("db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.", "counter", float64(stats.MaxLifetimeClosed))
+	})
+}
@@ -0,0 +1,322 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MigrationsTable хранит применённые версии
+const MigrationsTable = "schema_migrations"
+
+// migrationsLockKey - ключ pg_advisory_xact_lock, общий для всех экземпляров приложения
+const migrationsLockKey = 7_302_117_515
+
+var (
+	ErrChecksumMismatch   = errors.New("applied migration was modified")
+	ErrUnknownMigration   = errors.New("applied migration is missing from source")
+	ErrIrreversible       = errors.New("migration has no down script")
+	ErrNothingToRollback  = errors.New("no applied migrations")
+	ErrInvalidMigrationFS = errors.New("invalid migration file")
+)
+
+// Файлы миграций: <version>_<name>.up.sql и <version>_<name>.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum считается по up скрипту: его правка после применения - ошибка
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+type MigrationRecord struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (MigrationRecord) TableName() string {
+	return MigrationsTable
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool
+}
+
+type Migrator struct {
+	Database   *Db
+	Migrations []Migration
+}
+
+func NewMigrator(database *Db, source fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		Database:   database,
+		Migrations: migrations,
+	}, nil
+}
+
+// LoadMigrations читает миграции из корня source, отсортированные по версии
+func LoadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigrationFS, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigrationFS, entry.Name())
+		}
+		data, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d has names %s and %s", ErrInvalidMigrationFS, version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up script", ErrInvalidMigrationFS, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+// Up применяет все ожидающие миграции, каждую в своей транзакции
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	for _, migration := range m.Migrations {
+		done := false
+		err := m.locked(ctx, func(tx *gorm.DB, records map[int64]MigrationRecord) error {
+			// Другой экземпляр мог применить миграцию, пока мы ждали блокировку
+			if _, ok := records[migration.Version]; ok {
+				return nil
+			}
+			err := tx.Exec(migration.Up).Error
+			if err != nil {
+				return err
+			}
+			done = true
+			return tx.Create(&MigrationRecord{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum(),
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if done {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down откатывает последнюю применённую миграцию
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.locked(ctx, func(tx *gorm.DB, records map[int64]MigrationRecord) error {
+		if len(records) == 0 {
+			return ErrNothingToRollback
+		}
+		last := slices.Max(slices.Collect(maps.Keys(records)))
+		index := slices.IndexFunc(m.Migrations, func(migration Migration) bool {
+			return migration.Version == last
+		})
+		migration := m.Migrations[index]
+		if migration.Down == "" {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		err := tx.Exec(migration.Down).Error
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		reverted = &migration
+		return tx.Delete(&MigrationRecord{}, migration.Version).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// Status возвращает все известные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	err := m.ensureTable(m.Database.DB.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	records, err := loadRecords(m.Database.DB.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Migration: migration}
+		if record, ok := records[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			status.Modified = record.Checksum != migration.Checksum()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending возвращает количество неприменённых миграций
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// locked выполняет fn в транзакции под блокировкой миграций. Перед вызовом
+// проверяется, что применённые миграции не изменены и не удалены из исходников
+func (m *Migrator) locked(ctx context.Context, fn func(tx *gorm.DB, records map[int64]MigrationRecord) error) error {
+	return m.Database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// В SQLite запись и так сериализуется блокировкой базы
+		if tx.Dialector.Name() == "postgres" {
+			err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationsLockKey).Error
+			if err != nil {
+				return err
+			}
+		}
+		err := m.ensureTable(tx)
+		if err != nil {
+			return err
+		}
+		records, err := loadRecords(tx)
+		if err != nil {
+			return err
+		}
+		err = m.verify(records)
+		if err != nil {
+			return err
+		}
+		return fn(tx, records)
+	})
+}
+
+func (m *Migrator) ensureTable(tx *gorm.DB) error {
+	return tx.Exec(`CREATE TABLE IF NOT EXISTS ` + MigrationsTable + ` (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`).Error
+}
+
+func (m *Migrator) verify(records map[int64]MigrationRecord) error {
+	known := map[int64]Migration{}
+	for _, migration := range m.Migrations {
+		known[migration.Version] = migration
+	}
+	for version, record := range records {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, record.Name)
+		}
+		if record.Checksum != migration.Checksum() {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, record.Name)
+		}
+	}
+	return nil
+}
+
+func loadRecords(tx *gorm.DB) (map[int64]MigrationRecord, error) {
+	var list []MigrationRecord
+	err := tx.Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[int64]MigrationRecord, len(list))
+	for _, record := range list {
+		records[record.Version] = record
+	}
+	return records, nil
+}
+
+// CreateMigration создаёт пустую пару up/down в dir с версией из текущего времени
+func CreateMigration(dir, name string, now time.Time) (upPath, downPath string, err error) {
+	base := now.UTC().Format("20060102150405") + "_" + name
+	if !migrationFile.MatchString(base + ".up.sql") {
+		return "", "", fmt.Errorf("%w: name must match [a-z0-9_]+", ErrInvalidMigrationFS)
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", "", err
+	}
+	upPath = filepath.Join(dir, base+".up.sql")
+	downPath = filepath.Join(dir, base+".down.sql")
+	for _, path := range []string{upPath, downPath} {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		_, err = fmt.Fprintf(file, "-- %s\n", filepath.Base(path))
+		err = errors.Join(err, file.Close())
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return upPath, downPath, nil
+}
@@ -0,0 +1,217 @@
+package db_test
+
+import (
+	"adv-mod/internal/dbtest"
+	"adv-mod/migrations"
+	"adv-mod/pkg/db"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newTestSource() fstest.MapFS {
+	return fstest.MapFS{
+		"1_create_links.up.sql":   {Data: []byte("CREATE TABLE links (id INTEGER PRIMARY KEY, url TEXT);")},
+		"1_create_links.down.sql": {Data: []byte("DROP TABLE links;")},
+		"2_add_hash.up.sql":       {Data: []byte("ALTER TABLE links ADD COLUMN hash TEXT;\nCREATE INDEX idx_links_hash ON links (hash);")},
+		"2_add_hash.down.sql":     {Data: []byte("DROP INDEX idx_links_hash;\nALTER TABLE links DROP COLUMN hash;")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.Open(t)
+	migrator, err := db.NewMigrator(database, newTestSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].Version != 1 || applied[1].Version != 2 {
+		t.Fatalf("Expected versions 1 and 2 applied in order, got %+v", applied)
+	}
+	if !database.Migrator().HasColumn("links", "hash") {
+		t.Error("Expected links.hash to exist")
+	}
+
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected second up to be a no-op, got %+v", applied)
+	}
+
+	reverted, err := migrator.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Version != 2 {
+		t.Errorf("Expected version 2 reverted, got %d", reverted.Version)
+	}
+	if database.Migrator().HasColumn("links", "hash") {
+		t.Error("Expected links.hash to be dropped")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Expected only version 1 applied, got %+v", statuses)
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 {
+		t.Errorf("Expected 1 pending migration, got %d", pending)
+	}
+
+	_, err = migrator.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Down(ctx)
+	if !errors.Is(err, db.ErrNothingToRollback) {
+		t.Errorf("Expected ErrNothingToRollback, got %v", err)
+	}
+}
+
+func TestMigrateChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.Open(t)
+	source := newTestSource()
+	migrator, err := db.NewMigrator(database, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	source["1_create_links.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE links (id INTEGER PRIMARY KEY);")}
+	source["3_noop.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	migrator, err = db.NewMigrator(database, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up(ctx)
+	if !errors.Is(err, db.ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Modified || statuses[2].AppliedAt != nil {
+		t.Errorf("Expected version 1 modified and version 3 pending, got %+v", statuses)
+	}
+}
+
+func TestMigrateConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.Open(t)
+	var wg sync.WaitGroup
+	results := make([]int, 4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			migrator, err := db.NewMigrator(database, newTestSource())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			applied, err := migrator.Up(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = len(applied)
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range results {
+		total += n
+	}
+	if total != 2 {
+		t.Errorf("Expected each migration applied exactly once, got %d applications", total)
+	}
+}
+
+func TestLoadMigrationsInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		source fstest.MapFS
+	}{
+		{name: "Bad name", source: fstest.MapFS{"create links.up.sql": {Data: []byte("SELECT 1;")}}},
+		{name: "Only down", source: fstest.MapFS{"1_links.down.sql": {Data: []byte("SELECT 1;")}}},
+		{name: "Name mismatch", source: fstest.MapFS{
+			"1_links.up.sql": {Data: []byte("SELECT 1;")},
+			"1_users.up.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := db.LoadMigrations(tc.source)
+			if !errors.Is(err, db.ErrInvalidMigrationFS) {
+				t.Errorf("Expected ErrInvalidMigrationFS, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := db.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for _, migration := range list {
+		if migration.Down == "" {
+			t.Errorf("Migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	now := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+	upPath, downPath, err := db.CreateMigration(dir, "add_links", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(upPath) != "20261018123000_add_links.up.sql" || filepath.Base(downPath) != "20261018123000_add_links.down.sql" {
+		t.Errorf("Unexpected paths %s, %s", upPath, downPath)
+	}
+	list, err := db.LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Version != 20261018123000 {
+		t.Errorf("Expected created migration to load, got %+v", list)
+	}
+
+	_, _, err = db.CreateMigration(dir, "add_links", now)
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected existing files not to be overwritten, got %v", err)
+	}
+	_, _, err = db.CreateMigration(dir, "Add Links", now)
+	if !errors.Is(err, db.ErrInvalidMigrationFS) {
+		t.Errorf("Expected invalid name error, got %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidQuery = errors.New("invalid list query")
+
+// ListOptions - белые списки параметров запроса. Ключ - имя в query string, значение - колонка
+type ListOptions struct {
+	Filters      map[string]string
+	Sorts        map[string]string
+	DefaultSort  string
+	DefaultLimit int
+	MaxLimit     int
+}
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+type Filter struct {
+	Column   string
+	Operator string
+	Value    any
+}
+
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	Sort    []SortField
+	Filters []Filter
+}
+
+// Page - страница результата. Total считается только без курсора,
+// NextCursor есть, пока остались записи и сортировка по одному полю
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+}
+
+type Scope = func(*gorm.DB) *gorm.DB
+
+var operators = map[string]func(column clause.Column, value any) clause.Expression{
+	"eq":   func(c clause.Column, v any) clause.Expression { return clause.Eq{Column: c, Value: v} },
+	"ne":   func(c clause.Column, v any) clause.Expression { return clause.Neq{Column: c, Value: v} },
+	"lt":   func(c clause.Column, v any) clause.Expression { return clause.Lt{Column: c, Value: v} },
+	"lte":  func(c clause.Column, v any) clause.Expression { return clause.Lte{Column: c, Value: v} },
+	"gt":   func(c clause.Column, v any) clause.Expression { return clause.Gt{Column: c, Value: v} },
+	"gte":  func(c clause.Column, v any) clause.Expression { return clause.Gte{Column: c, Value: v} },
+	"like": func(c clause.Column, v any) clause.Expression { return clause.Like{Column: c, Value: v} },
+}
+
+// Repository - CRUD для модели T. Если в T есть gorm.DeletedAt, Delete мягкий
+type Repository[T any] struct {
+	Database *Db
+	Options  ListOptions
+	schema   *schema.Schema
+}
+
+// NewRepository паникует, если T не модель gorm или белые списки ссылаются на неизвестные колонки
+func NewRepository[T any](database *Db, options ListOptions) *Repository[T] {
+	s, err := schema.Parse(new(T), &sync.Map{}, database.NamingStrategy)
+	if err != nil {
+		panic(err)
+	}
+	if s.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("db: %s has no primary key", s.Name))
+	}
+	if options.DefaultSort == "" {
+		options.DefaultSort = s.PrioritizedPrimaryField.DBName
+	}
+	if options.DefaultLimit <= 0 {
+		options.DefaultLimit = DefaultLimit
+	}
+	if options.MaxLimit <= 0 {
+		options.MaxLimit = MaxLimit
+	}
+	columns := []string{strings.TrimPrefix(options.DefaultSort, "-")}
+	for _, column := range options.Filters {
+		columns = append(columns, column)
+	}
+	for _, column := range options.Sorts {
+		columns = append(columns, column)
+	}
+	for _, column := range columns {
+		if s.LookUpField(column) == nil {
+			panic(fmt.Sprintf("db: %s has no column %q", s.Name, column))
+		}
+	}
+	return &Repository[T]{
+		Database: database,
+		Options:  options,
+		schema:   s,
+	}
+}
+
+// WithTx возвращает копию репозитория, работающую внутри транзакции tx
+func (repo *Repository[T]) WithTx(tx *Db) *Repository[T] {
+	copied := *repo
+	copied.Database = tx
+	return &copied
+}
+
+// WithTx выполняет fn в транзакции: ошибка или паника откатывают её
+func WithTx(database *Db, fn func(tx *Db) error) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		return fn(&Db{tx})
+	})
+}
+
+func (repo *Repository[T]) Create(item *T) (*T, error) {
+	result := repo.Database.DB.Create(item)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return item, nil
+}
+
+func (repo *Repository[T]) GetByID(id uint) (*T, error) {
+	var item T
+	result := repo.Database.DB.First(&item, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+// Update сохраняет ненулевые поля item
+func (repo *Repository[T]) Update(item *T) (*T, error) {
+	result := repo.Database.DB.Updates(item)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return item, nil
+}
+
+func (repo *Repository[T]) Delete(id uint) error {
+	result := repo.Database.DB.Delete(new(T), id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// HardDelete удаляет запись физически, в том числе мягко удалённую
+func (repo *Repository[T]) HardDelete(id uint) error {
+	result := repo.Database.DB.Unscoped().Delete(new(T), id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore снимает отметку мягкого удаления
+func (repo *Repository[T]) Restore(id uint) error {
+	if repo.schema.LookUpField("deleted_at") == nil {
+		return fmt.Errorf("db: %s does not support soft delete", repo.schema.Name)
+	}
+	result := repo.Database.DB.Unscoped().Model(new(T)).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		Where("deleted_at IS NOT NULL").
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ParseQuery разбирает limit, offset, cursor, sort=-created_at,name и фильтры
+// вида name=value или price[gte]=10. Разрешены только колонки из Options
+func (repo *Repository[T]) ParseQuery(values url.Values) (ListQuery, error) {
+	query := ListQuery{
+		Limit:  repo.Options.DefaultLimit,
+		Cursor: values.Get("cursor"),
+	}
+	var err error
+	if raw := values.Get("limit"); raw != "" {
+		query.Limit, err = strconv.Atoi(raw)
+		if err != nil || query.Limit < 1 || query.Limit > repo.Options.MaxLimit {
+			return ListQuery{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, repo.Options.MaxLimit)
+		}
+	}
+	if raw := values.Get("offset"); raw != "" {
+		query.Offset, err = strconv.Atoi(raw)
+		if err != nil || query.Offset < 0 {
+			return ListQuery{}, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidQuery)
+		}
+	}
+	if raw := values.Get("sort"); raw != "" {
+		for name := range strings.SplitSeq(raw, ",") {
+			desc := strings.HasPrefix(name, "-")
+			column, ok := repo.Options.Sorts[strings.TrimPrefix(name, "-")]
+			if !ok {
+				return ListQuery{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, name)
+			}
+			query.Sort = append(query.Sort, SortField{Column: column, Desc: desc})
+		}
+	}
+	for key, list := range values {
+		name, operator := key, "eq"
+		if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+			name, operator = key[:i], key[i+1:len(key)-1]
+		}
+		column, ok := repo.Options.Filters[name]
+		if !ok {
+			continue
+		}
+		if _, ok := operators[operator]; !ok {
+			return ListQuery{}, fmt.Errorf("%w: unknown operator %q for %q", ErrInvalidQuery, operator, name)
+		}
+		value, err := convert(repo.schema.LookUpField(column), list[0])
+		if err != nil {
+			return ListQuery{}, fmt.Errorf("%w: %s: %v", ErrInvalidQuery, key, err)
+		}
+		query.Filters = append(query.Filters, Filter{Column: column, Operator: operator, Value: value})
+	}
+	return query, nil
+}
+
+// List возвращает страницу по смещению или, если задан Cursor, по ключу сортировки.
+// scopes добавляют условия, которые не приходят от клиента, например владельца записи
+func (repo *Repository[T]) List(query ListQuery, scopes ...Scope) (*Page[T], error) {
+	if query.Limit <= 0 {
+		query.Limit = repo.Options.DefaultLimit
+	}
+	sort := query.Sort
+	if len(sort) == 0 {
+		sort = []SortField{{
+			Column: strings.TrimPrefix(repo.Options.DefaultSort, "-"),
+			Desc:   strings.HasPrefix(repo.Options.DefaultSort, "-"),
+		}}
+	}
+	if query.Cursor != "" && (query.Offset != 0 || len(sort) > 1) {
+		return nil, fmt.Errorf("%w: cursor can not be combined with offset or several sort fields", ErrInvalidQuery)
+	}
+
+	filtered := func() *gorm.DB {
+		tx := repo.Database.DB.Model(new(T)).Scopes(scopes...)
+		for _, filter := range query.Filters {
+			tx = tx.Where(operators[filter.Operator](clause.Column{Name: filter.Column}, filter.Value))
+		}
+		return tx
+	}
+	page := &Page[T]{
+		Items:  []T{},
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	tx := filtered()
+	if query.Cursor == "" {
+		var total int64
+		result := filtered().Count(&total)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		page.Total = &total
+	} else {
+		condition, err := repo.afterCursor(query.Cursor, sort[0])
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(condition)
+	}
+
+	primary := repo.schema.PrioritizedPrimaryField.DBName
+	for _, field := range sort {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: field.Column}, Desc: field.Desc})
+	}
+	if sort[0].Column != primary {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: primary}, Desc: sort[len(sort)-1].Desc})
+	}
+	// Лишняя запись показывает, есть ли следующая страница
+	result := tx.Limit(query.Limit + 1).Offset(query.Offset).Find(&page.Items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		if len(sort) == 1 {
+			cursor, err := repo.cursorAt(&page.Items[len(page.Items)-1], sort[0])
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor = cursor
+		}
+	}
+	return page, nil
+}
+
+type cursor struct {
+	Value json.RawMessage `json:"v"`
+	Id    json.RawMessage `json:"id"`
+}
+
+func (repo *Repository[T]) cursorAt(item *T, sort SortField) (string, error) {
+	rv := reflect.ValueOf(item).Elem()
+	value, _ := repo.schema.LookUpField(sort.Column).ValueOf(context.Background(), rv)
+	id, _ := repo.schema.PrioritizedPrimaryField.ValueOf(context.Background(), rv)
+	var c cursor
+	var err error
+	c.Value, err = json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	c.Id, err = json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// afterCursor строит условие keyset пагинации. Колонка сортировки должна быть NOT NULL
+func (repo *Repository[T]) afterCursor(raw string, sort SortField) (clause.Expression, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+	var c cursor
+	if json.Unmarshal(data, &c) != nil {
+		return nil, invalid
+	}
+	sortField := repo.schema.LookUpField(sort.Column)
+	primaryField := repo.schema.PrioritizedPrimaryField
+	value := reflect.New(sortField.FieldType)
+	id := reflect.New(primaryField.FieldType)
+	if json.Unmarshal(c.Value, value.Interface()) != nil || json.Unmarshal(c.Id, id.Interface()) != nil {
+		return nil, invalid
+	}
+
+	after := func(column clause.Column, v any) clause.Expression {
+		if sort.Desc {
+			return clause.Lt{Column: column, Value: v}
+		}
+		return clause.Gt{Column: column, Value: v}
+	}
+	primary := clause.Column{Name: primaryField.DBName}
+	if sortField == primaryField {
+		return after(primary, id.Elem().Interface()), nil
+	}
+	column := clause.Column{Name: sortField.DBName}
+	return clause.Or(
+		after(column, value.Elem().Interface()),
+		clause.And(
+			clause.Eq{Column: column, Value: value.Elem().Interface()},
+			after(primary, id.Elem().Interface()),
+		),
+	), nil
+}
+
+// convert приводит строку из query string к типу поля модели
+func convert(field *schema.Field, raw string) (any, error) {
+	t := field.FieldType
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return time.Parse(time.RFC3339, raw)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	}
+	return nil, fmt.Errorf("unsupported filter type %s", t)
+}
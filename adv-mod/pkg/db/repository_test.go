@@ -0,0 +1,233 @@
+package db_test
+
+import (
+	"adv-mod/internal/dbtest"
+	"adv-mod/pkg/db"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type Item struct {
+	gorm.Model
+	Name     string
+	Category string
+	Price    int
+	OwnerId  uint
+}
+
+func newTestRepository(t *testing.T) *db.Repository[Item] {
+	t.Helper()
+	return db.NewRepository[Item](dbtest.Open(t, &Item{}), db.ListOptions{
+		Filters: map[string]string{
+			"category": "category",
+			"price":    "price",
+		},
+		Sorts: map[string]string{
+			"price":      "price",
+			"name":       "name",
+			"created_at": "created_at",
+		},
+	})
+}
+
+func seed(t *testing.T, repo *db.Repository[Item], n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		category := "book"
+		if i%2 == 0 {
+			category = "pen"
+		}
+		_, err := repo.Create(&Item{
+			Name:     fmt.Sprintf("item-%02d", i),
+			Category: category,
+			Price:    (i % 4) * 10,
+			OwnerId:  uint(i%2 + 1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepositoryCrud(t *testing.T) {
+	repo := newTestRepository(t)
+	created, err := repo.Create(&Item{Name: "pen", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.Update(&Item{Model: gorm.Model{ID: created.ID}, Price: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := repo.GetByID(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Price != 20 || item.Name != "pen" {
+		t.Errorf("Expected partial update, got %+v", item)
+	}
+
+	if err := repo.Delete(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.GetByID(created.ID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected soft deleted item to be hidden, got %v", err)
+	}
+	if err := repo.Delete(created.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected second delete to report not found, got %v", err)
+	}
+
+	if err := repo.Restore(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(created.ID); err != nil {
+		t.Errorf("Expected restored item, got %v", err)
+	}
+
+	if err := repo.HardDelete(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(created.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected hard deleted item to be gone, got %v", err)
+	}
+	if _, err := repo.Update(&Item{Model: gorm.Model{ID: 100}, Price: 1}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected update of missing item to fail, got %v", err)
+	}
+}
+
+func TestRepositoryOffsetPagination(t *testing.T) {
+	repo := newTestRepository(t)
+	seed(t, repo, 10)
+
+	query, err := repo.ParseQuery(url.Values{
+		"category":   {"book"},
+		"price[gte]": {"10"},
+		"sort":       {"-price,name"},
+		"limit":      {"2"},
+		"offset":     {"1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := repo.List(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// book: 1(10), 3(30), 5(10), 7(30), 9(10) → по цене убыв., имени: 3, 7, 1, 5, 9
+	if page.Total == nil || *page.Total != 5 {
+		t.Fatalf("Expected total 5, got %v", page.Total)
+	}
+	if len(page.Items) != 2 || page.Items[0].Name != "item-07" || page.Items[1].Name != "item-01" {
+		t.Errorf("Unexpected page %+v", page.Items)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no cursor for several sort fields, got %q", page.NextCursor)
+	}
+}
+
+func TestRepositoryCursorPagination(t *testing.T) {
+	repo := newTestRepository(t)
+	seed(t, repo, 7)
+
+	ownedBy := func(owner uint) db.Scope {
+		return func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("owner_id = ?", owner)
+		}
+	}
+	var names []string
+	values := url.Values{"sort": {"-price"}, "limit": {"2"}}
+	for range 10 {
+		query, err := repo.ParseQuery(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := repo.List(query, ownedBy(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+	// owner 2: 1(10), 3(30), 5(10), 7(30) → цена убыв., затем id убыв.
+	expected := fmt.Sprint([]string{"item-07", "item-03", "item-05", "item-01"})
+	if fmt.Sprint(names) != expected {
+		t.Errorf("Expected %s, got %v", expected, names)
+	}
+}
+
+func TestRepositoryParseQueryRejects(t *testing.T) {
+	repo := newTestRepository(t)
+	testCases := []struct {
+		name   string
+		values url.Values
+	}{
+		{name: "Unknown sort", values: url.Values{"sort": {"password"}}},
+		{name: "Unknown operator", values: url.Values{"price[drop]": {"1"}}},
+		{name: "Bad value", values: url.Values{"price": {"cheap"}}},
+		{name: "Limit too large", values: url.Values{"limit": {"1000"}}},
+		{name: "Negative offset", values: url.Values{"offset": {"-1"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := repo.ParseQuery(tc.values)
+			if !errors.Is(err, db.ErrInvalidQuery) {
+				t.Errorf("Expected ErrInvalidQuery, got %v", err)
+			}
+		})
+	}
+
+	query, err := repo.ParseQuery(url.Values{"owner_id": {"1"}})
+	if err != nil || len(query.Filters) != 0 {
+		t.Errorf("Expected non-whitelisted filter to be ignored, got %+v, %v", query, err)
+	}
+	_, err = repo.List(db.ListQuery{Cursor: "not-a-cursor"})
+	if !errors.Is(err, db.ErrInvalidQuery) {
+		t.Errorf("Expected malformed cursor error, got %v", err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	repo := newTestRepository(t)
+	err := db.WithTx(repo.Database, func(tx *db.Db) error {
+		if _, err := repo.WithTx(tx).Create(&Item{Name: "rolled back"}); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("Expected transaction error")
+	}
+	page, err := repo.List(db.ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *page.Total != 0 {
+		t.Errorf("Expected rollback, got %d items", *page.Total)
+	}
+
+	err = db.WithTx(repo.Database, func(tx *db.Db) error {
+		_, err := repo.WithTx(tx).Create(&Item{Name: "committed"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = repo.List(db.ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *page.Total != 1 {
+		t.Errorf("Expected commit, got %d items", *page.Total)
+	}
+}
@@ -0,0 +1,35 @@
+package di
+
+import (
+	"adv-mod/internal/session"
+	"adv-mod/internal/user"
+	"adv-mod/internal/verification"
+)
+
+type IUserRepository interface {
+	Create(user *user.User) (*user.User, error)
+	FindByEmail(email string) (*user.User, error)
+	FindById(id uint) (*user.User, error)
+	Update(user *user.User) (*user.User, error)
+	List(limit, offset int) ([]user.User, int64, error)
+	UpdateRole(id uint, role string) error
+	MarkEmailVerified(id uint, email string) (bool, error)
+	SetPassword(id uint, hashedPassword string) error
+	ChangeEmail(id uint, email string) error
+	Delete(id uint) error
+}
+
+type IRefreshTokenRepository interface {
+	Create(token *session.RefreshToken) (*session.RefreshToken, error)
+	FindByHash(hash string) (*session.RefreshToken, error)
+	MarkRotated(id uint) (bool, error)
+	RevokeFamily(familyId string) error
+	RevokeUser(userId uint) error
+}
+
+type IVerificationTokenRepository interface {
+	Create(token *verification.Token) (*verification.Token, error)
+	FindByHash(hash, purpose string) (*verification.Token, error)
+	MarkUsed(id uint) (bool, error)
+	InvalidateUser(userId uint, purpose string) error
+}
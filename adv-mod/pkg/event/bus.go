@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// DefaultBufferSize - очередь одного подписчика, если NewBus получил 0
+const DefaultBufferSize = 256
+
+// Topic связывает имя темы с типом данных, чтобы Publish и Subscribe
+// не расходились в типе payload
+type Topic[T any] struct {
+	Name string
+}
+
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{Name: name}
+}
+
+// Bus - шина событий внутри процесса. У каждого подписчика своя буферизованная
+// очередь и горутина: события темы приходят ему в порядке публикации,
+// а медленный или упавший подписчик не мешает остальным
+type Bus struct {
+	BufferSize int
+
+	mu          sync.RWMutex
+	subscribers map[string][]*subscriber
+	closed      bool
+	abort       chan struct{}
+	abortOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+type subscriber struct {
+	topic  string
+	name   string
+	queue  chan any
+	handle func(payload any)
+}
+
+func NewBus(bufferSize int) *Bus {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Bus{
+		BufferSize:  bufferSize,
+		subscribers: map[string][]*subscriber{},
+		abort:       make(chan struct{}),
+	}
+}
+
+// Subscribe добавляет обработчик темы, name нужен для логов.
+// После Shutdown вызов ничего не делает
+func Subscribe[T any](bus *Bus, topic Topic[T], name string, handler func(payload T)) {
+	sub := &subscriber{
+		topic: topic.Name,
+		name:  name,
+		queue: make(chan any, bus.BufferSize),
+		handle: func(payload any) {
+			handler(payload.(T))
+		},
+	}
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if bus.closed {
+		return
+	}
+	bus.subscribers[topic.Name] = append(bus.subscribers[topic.Name], sub)
+	bus.wg.Add(1)
+	go bus.run(sub)
+}
+
+// Publish не блокирует: если очередь подписчика заполнена, событие для него
+// отбрасывается, чтобы запрос не ждал обработчиков. Возвращает false, если
+// событие получили не все подписчики или шина закрыта. На nil ничего не делает
+func Publish[T any](bus *Bus, topic Topic[T], payload T) bool {
+	if bus == nil {
+		return false
+	}
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	if bus.closed {
+		return false
+	}
+	delivered := true
+	for _, sub := range bus.subscribers[topic.Name] {
+		select {
+		case sub.queue <- payload:
+		default:
+			delivered = false
+			slog.Warn("event dropped, subscriber queue is full", "topic", topic.Name, "subscriber", sub.name)
+		}
+	}
+	return delivered
+}
+
+// Shutdown перестаёт принимать события и ждёт, пока подписчики разберут
+// очереди. Если ctx истёк раньше, оставшиеся события отбрасываются, а
+// подписчики завершаются после текущего обработчика
+func (bus *Bus) Shutdown(ctx context.Context) error {
+	if bus == nil {
+		return nil
+	}
+	bus.mu.Lock()
+	if !bus.closed {
+		bus.closed = true
+		for _, subs := range bus.subscribers {
+			for _, sub := range subs {
+				close(sub.queue)
+			}
+		}
+	}
+	bus.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		bus.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		bus.abortOnce.Do(func() { close(bus.abort) })
+		return ctx.Err()
+	}
+}
+
+func (bus *Bus) run(sub *subscriber) {
+	defer bus.wg.Done()
+	for {
+		// abort проверяется первым: из двух готовых случаев select выбрал бы случайный
+		select {
+		case <-bus.abort:
+			return
+		default:
+		}
+		select {
+		case payload, ok := <-sub.queue:
+			if !ok {
+				return
+			}
+			bus.deliver(sub, payload)
+		case <-bus.abort:
+			return
+		}
+	}
+}
+
+// deliver изолирует панику обработчика: подписчик продолжает получать события
+func (bus *Bus) deliver(sub *subscriber, payload any) {
+	defer func() {
+		err := recover()
+		if err == nil {
+			return
+		}
+		slog.Error("event subscriber panic",
+			"error", err,
+			"topic", sub.topic,
+			"subscriber", sub.name,
+			"stack", string(debug.Stack()),
+		)
+	}()
+	sub.handle(payload)
+}
@@ -0,0 +1,220 @@
+package event_test
+
+import (
+	"adv-mod/pkg/event"
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	numbers = event.NewTopic[int]("numbers")
+	words   = event.NewTopic[string]("words")
+)
+
+// recorder собирает полученные события, обработчики вызываются из горутин шины
+type recorder[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (r *recorder[T]) add(item T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = append(r.items, item)
+}
+
+func (r *recorder[T]) get() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.items)
+}
+
+func shutdown(t *testing.T, bus *event.Bus) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := bus.Shutdown(ctx); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+}
+
+// checkNoLeaks сравнивает число горутин с исходным. Горутины завершаются
+// асинхронно, поэтому число проверяется несколько раз
+func checkNoLeaks(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("Expected %d goroutines, got %d:\n%s", before, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOrderingPerTopic(t *testing.T) {
+	bus := event.NewBus(1000)
+	var first, second recorder[int]
+	var other recorder[string]
+	event.Subscribe(bus, numbers, "first", first.add)
+	event.Subscribe(bus, numbers, "second", func(n int) {
+		// Медленный подписчик не должен менять порядок
+		if n%100 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		second.add(n)
+	})
+	event.Subscribe(bus, words, "other", other.add)
+
+	want := make([]int, 0, 500)
+	for i := range 500 {
+		want = append(want, i)
+		if !event.Publish(bus, numbers, i) {
+			t.Fatalf("Expected event %d to be delivered", i)
+		}
+	}
+	event.Publish(bus, words, "hello")
+	shutdown(t, bus)
+
+	if got := first.get(); !slices.Equal(got, want) {
+		t.Errorf("First subscriber got events out of order: %v", got)
+	}
+	if got := second.get(); !slices.Equal(got, want) {
+		t.Errorf("Second subscriber got events out of order: %v", got)
+	}
+	if got := other.get(); !slices.Equal(got, []string{"hello"}) {
+		t.Errorf("Expected only words in other topic, got %v", got)
+	}
+}
+
+func TestPanicIsolation(t *testing.T) {
+	bus := event.NewBus(10)
+	var healthy, flaky recorder[int]
+	event.Subscribe(bus, numbers, "panicking", func(n int) {
+		panic("boom")
+	})
+	event.Subscribe(bus, numbers, "flaky", func(n int) {
+		if n == 1 {
+			panic("boom")
+		}
+		flaky.add(n)
+	})
+	event.Subscribe(bus, numbers, "healthy", healthy.add)
+
+	for i := range 3 {
+		event.Publish(bus, numbers, i)
+	}
+	shutdown(t, bus)
+
+	if got := healthy.get(); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("Expected healthy subscriber to get all events, got %v", got)
+	}
+	if got := flaky.get(); !slices.Equal(got, []int{0, 2}) {
+		t.Errorf("Expected subscriber to survive its panic, got %v", got)
+	}
+}
+
+func TestShutdownDrainsPendingEvents(t *testing.T) {
+	before := runtime.NumGoroutine()
+	bus := event.NewBus(100)
+	release := make(chan struct{})
+	var got recorder[int]
+	event.Subscribe(bus, numbers, "slow", func(n int) {
+		<-release
+		got.add(n)
+	})
+	for i := range 50 {
+		event.Publish(bus, numbers, i)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	shutdown(t, bus)
+
+	if n := len(got.get()); n != 50 {
+		t.Errorf("Expected all 50 events to be handled before shutdown returns, got %d", n)
+	}
+	if event.Publish(bus, numbers, 1) {
+		t.Error("Expected publish after shutdown to fail")
+	}
+	event.Subscribe(bus, numbers, "late", func(n int) {})
+	checkNoLeaks(t, before)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	before := runtime.NumGoroutine()
+	bus := event.NewBus(100)
+	release := make(chan struct{})
+	var got recorder[int]
+	event.Subscribe(bus, numbers, "stuck", func(n int) {
+		<-release
+		got.add(n)
+	})
+	for i := range 10 {
+		event.Publish(bus, numbers, i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	close(release)
+	checkNoLeaks(t, before)
+	// Текущий обработчик завершается, остальные события отбрасываются
+	if n := len(got.get()); n != 1 {
+		t.Errorf("Expected only the running handler to finish, got %d events", n)
+	}
+}
+
+func TestPublishDropsWhenQueueIsFull(t *testing.T) {
+	bus := event.NewBus(1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var got recorder[int]
+	event.Subscribe(bus, numbers, "blocked", func(n int) {
+		if n == 0 {
+			close(started)
+			<-release
+		}
+		got.add(n)
+	})
+
+	event.Publish(bus, numbers, 0)
+	<-started
+	if !event.Publish(bus, numbers, 1) {
+		t.Fatal("Expected event to fit into the queue")
+	}
+	if event.Publish(bus, numbers, 2) {
+		t.Error("Expected event to be dropped when the queue is full")
+	}
+	close(release)
+	shutdown(t, bus)
+	if items := got.get(); !slices.Equal(items, []int{0, 1}) {
+		t.Errorf("Expected dropped event to be lost, got %v", items)
+	}
+}
+
+func TestPublishWithoutSubscribers(t *testing.T) {
+	bus := event.NewBus(0)
+	if !event.Publish(bus, words, "nobody listens") {
+		t.Error("Expected publish without subscribers to succeed")
+	}
+	shutdown(t, bus)
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *event.Bus
+	if event.Publish(bus, numbers, 1) {
+		t.Error("Expected nil bus to drop events")
+	}
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected nil bus shutdown to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const DefaultTTL = 24 * time.Hour
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+type JWTData struct {
+	Email     string
+	UserId    uint
+	Role      string
+	TokenId   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	UserId uint   `json:"uid,omitempty"`
+	Role   string `json:"role,omitempty"`
+}
+
+type JWT struct {
+	Secret string
+	TTL    time.Duration
+}
+
+func NewJWT(secret string) *JWT {
+	return &JWT{
+		Secret: secret,
+		TTL:    DefaultTTL,
+	}
+}
+
+// Create подписывает HS256 токен, в котором subject - email пользователя
+func (j *JWT) Create(data JWTData) (string, error) {
+	tokenId, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	c := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   data.Email,
+			ID:        tokenId,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.TTL)),
+		},
+		UserId: data.UserId,
+		Role:   data.Role,
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	return t.SignedString([]byte(j.Secret))
+}
+
+// Parse проверяет подпись и срок действия токена
+func (j *JWT) Parse(token string) (*JWTData, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(t *jwt.Token) (any, error) {
+		return []byte(j.Secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil || c.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return &JWTData{
+		Email:     c.Subject,
+		UserId:    c.UserId,
+		Role:      c.Role,
+		TokenId:   c.ID,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiresAt: c.ExpiresAt.Time,
+	}, nil
+}
+
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
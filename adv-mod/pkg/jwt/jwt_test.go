@@ -0,0 +1,66 @@
+package jwt_test
+
+import (
+	"adv-mod/pkg/jwt"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJWTCreate(t *testing.T) {
+	const email = "a@a.ru"
+	jwtService := jwt.NewJWT("/2+XnmJGz1j3ehIVI/5P9kl+CghrE3DcS7rnT+qar5w=")
+	token, err := jwtService.Create(jwt.JWTData{
+		Email:  email,
+		UserId: 7,
+		Role:   "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := jwtService.Parse(token)
+	if err != nil {
+		t.Fatalf("Token is invalid: %v", err)
+	}
+	if data.Email != email {
+		t.Errorf("Expected email %s, got %s", email, data.Email)
+	}
+	if data.UserId != 7 {
+		t.Errorf("Expected user id 7, got %d", data.UserId)
+	}
+	if data.Role != "admin" {
+		t.Errorf("Expected role admin, got %q", data.Role)
+	}
+	if data.TokenId == "" {
+		t.Error("Token id is empty")
+	}
+	if !data.ExpiresAt.After(data.IssuedAt) {
+		t.Errorf("Expiry %v is not after issue time %v", data.ExpiresAt, data.IssuedAt)
+	}
+}
+
+func TestJWTWrongSecret(t *testing.T) {
+	token, err := jwt.NewJWT("secret").Create(jwt.JWTData{Email: "a@a.ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.NewJWT("other").Parse(token)
+	if !errors.Is(err, jwt.ErrInvalidToken) {
+		t.Errorf("Expected %v, got %v", jwt.ErrInvalidToken, err)
+	}
+}
+
+func TestJWTExpired(t *testing.T) {
+	jwtService := &jwt.JWT{
+		Secret: "secret",
+		TTL:    -time.Minute,
+	}
+	token, err := jwtService.Create(jwt.JWTData{Email: "a@a.ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwtService.Parse(token)
+	if !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("Expected %v, got %v", jwt.ErrTokenExpired, err)
+	}
+}
@@ -0,0 +1,45 @@
+package mailer_test
+
+import (
+	"adv-mod/pkg/mailer"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := mailer.NewFileMailer(dir, "no-reply@localhost")
+	err := m.Send(mailer.Message{
+		To:      "a@a.ru",
+		Subject: "Привет",
+		Body:    "line1\nline2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(entries))
+	}
+	data, _ := os.ReadFile(dir + "/" + entries[0].Name())
+	for _, want := range []string{"From: no-reply@localhost\r\n", "To: a@a.ru\r\n", "\r\n\r\nline1\r\nline2"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Mail does not contain %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	mailers := []mailer.Mailer{
+		mailer.NewMemoryMailer(),
+		mailer.NewFileMailer(t.TempDir(), "no-reply@localhost"),
+	}
+	for _, m := range mailers {
+		err := m.Send(mailer.Message{To: "a@a.ru\r\nBcc: victim@b.ru", Subject: "x"})
+		if !errors.Is(err, mailer.ErrHeaderInjection) {
+			t.Errorf("%T: expected %v, got %v", m, mailer.ErrHeaderInjection, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryMailer складывает письма в память. Используется в тестах
+type MemoryMailer struct {
+	mu     sync.Mutex
+	outbox []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	_, err := format("", msg)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outbox = append(m.outbox, msg)
+	return nil
+}
+
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.outbox...)
+}
+
+// FileMailer пишет каждое письмо в отдельный .eml файл. Подходит для разработки без SMTP
+type FileMailer struct {
+	Dir  string
+	From string
+
+	mu sync.Mutex
+	n  int
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{
+		Dir:  dir,
+		From: from,
+	}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	data, err := format(m.From, msg)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(m.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.n++
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().Format("20060102T150405"), m.n)
+	m.mu.Unlock()
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+}
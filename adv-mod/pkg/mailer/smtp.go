@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"adv-mod/configs"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func NewSMTPMailer(conf configs.MailConfig) *SMTPMailer {
+	var auth smtp.Auth
+	if conf.Username != "" {
+		auth = smtp.PlainAuth("", conf.Username, conf.Password, conf.SMTPHost)
+	}
+	return &SMTPMailer{
+		Addr: net.JoinHostPort(conf.SMTPHost, strconv.Itoa(conf.SMTPPort)),
+		From: conf.From,
+		Auth: auth,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	data, err := format(m.From, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, data)
+}
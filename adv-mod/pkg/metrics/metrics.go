@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets - границы гистограммы задержек в секундах
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// labelSep не встречается в значениях меток, поэтому годится для ключа серии
+const labelSep = "\xff"
+
+// family - метрика с набором серий, различающихся значениями меток
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// Только для гистограмм
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, labelSep)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter только растёт. Методы на nil ничего не делают, чтобы метрики были необязательной зависимостью
+type Counter struct {
+	family *family
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if c == nil {
+		return
+	}
+	if value < 0 {
+		panic("metrics: counter can not decrease")
+	}
+	c.family.mu.Lock()
+	defer c.family.mu.Unlock()
+	c.family.get(labelValues).value += value
+}
+
+type Gauge struct {
+	family *family
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	g.family.get(labelValues).value = value
+}
+
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	g.family.get(labelValues).value += value
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+type Histogram struct {
+	family  *family
+	buckets []float64
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+	h.family.mu.Lock()
+	defer h.family.mu.Unlock()
+	s := h.family.get(labelValues)
+	if s.counts == nil {
+		s.buckets = h.buckets
+		s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func validBuckets(buckets []float64) []float64 {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	buckets = slices.Compact(buckets)
+	if len(buckets) > 0 && math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+	return buckets
+}
@@ -0,0 +1,115 @@
+package metrics_test
+
+import (
+	"adv-mod/pkg/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.NewCounter("requests_total", "Requests.", "method", "path")
+	inFlight := registry.NewGauge("in_flight", "In flight.")
+	latency := registry.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "method")
+	registry.Collect(func(emit func(name, help, kind string, value float64)) {
+		emit("pool_open", "Open connections.", "gauge", 3)
+	})
+
+	requests.Inc("GET", "/b")
+	requests.Add(2, "GET", "/a\"\n")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(5, "GET")
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/a\"\n"} 2
+requests_total{method="GET",path="/b"} 1
+# HELP in_flight In flight.
+# TYPE in_flight gauge
+in_flight 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 1
+latency_seconds_bucket{method="GET",le="1"} 2
+latency_seconds_bucket{method="GET",le="+Inf"} 3
+latency_seconds_sum{method="GET"} 5.55
+latency_seconds_count{method="GET"} 3
+# HELP pool_open Open connections.
+# TYPE pool_open gauge
+pool_open 3
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := registry.NewCounter("events_total", "Events.", "kind")
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				counter.Inc("a")
+			}
+		}()
+	}
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `events_total{kind="a"} 5000`) {
+		t.Errorf("Expected 5000 events, got:\n%s", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != metrics.ContentType {
+		t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRegistryRejectsMisuse(t *testing.T) {
+	testCases := []struct {
+		name string
+		fn   func(registry *metrics.Registry)
+	}{
+		{name: "Duplicate", fn: func(registry *metrics.Registry) {
+			registry.NewCounter("a_total", "A.")
+			registry.NewGauge("a_total", "A.")
+		}},
+		{name: "Invalid name", fn: func(registry *metrics.Registry) { registry.NewCounter("a-total", "A.") }},
+		{name: "Reserved label", fn: func(registry *metrics.Registry) { registry.NewHistogram("a", "A.", nil, "le") }},
+		{name: "Wrong label count", fn: func(registry *metrics.Registry) { registry.NewCounter("a_total", "A.", "x").Inc() }},
+		{name: "Negative counter", fn: func(registry *metrics.Registry) { registry.NewCounter("a_total", "A.").Add(-1) }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected panic")
+				}
+			}()
+			tc.fn(metrics.NewRegistry())
+		})
+	}
+}
+
+func TestNilMetricsAreNoop(t *testing.T) {
+	var counter *metrics.Counter
+	var gauge *metrics.Gauge
+	var histogram *metrics.Histogram
+	counter.Inc()
+	gauge.Set(1)
+	histogram.Observe(1)
+}
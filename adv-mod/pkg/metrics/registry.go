@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType - текстовый формат экспозиции Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// CollectFunc вызывается при каждом чтении /metrics и сообщает текущие значения,
+// например статистику пула соединений
+type CollectFunc func(emit func(name, help, kind string, value float64))
+
+type Registry struct {
+	mu         sync.Mutex
+	families   []*family
+	names      map[string]bool
+	collectors []CollectFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: map[string]bool{},
+	}
+}
+
+func (registry *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{family: registry.register(name, help, typeCounter, labels)}
+}
+
+func (registry *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{family: registry.register(name, help, typeGauge, labels)}
+}
+
+// NewHistogram с пустыми buckets использует DefaultBuckets
+func (registry *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{
+		family:  registry.register(name, help, typeHistogram, labels),
+		buckets: validBuckets(buckets),
+	}
+}
+
+// Collect добавляет метрики, значения которых читаются в момент запроса
+func (registry *Registry) Collect(fn CollectFunc) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.collectors = append(registry.collectors, fn)
+}
+
+// register паникует на недопустимом или повторном имени: это ошибка программиста
+func (registry *Registry) register(name, help, kind string, labels []string) *family {
+	if !validName.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, label := range labels {
+		if !validName.MatchString(label) || strings.HasPrefix(label, "__") || label == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %s", label, name))
+		}
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.names[name] {
+		panic(fmt.Sprintf("metrics: %s is already registered", name))
+	}
+	registry.names[name] = true
+	f := &family{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: slices.Clone(labels),
+		series: map[string]*series{},
+	}
+	registry.families = append(registry.families, f)
+	return f
+}
+
+// WriteTo пишет все метрики в текстовом формате Prometheus
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mu.Lock()
+	families := slices.Clone(registry.families)
+	collectors := slices.Clone(registry.collectors)
+	registry.mu.Unlock()
+
+	counter := &countingWriter{w: w}
+	out := bufio.NewWriter(counter)
+	for _, f := range families {
+		f.write(out)
+	}
+	for _, collect := range collectors {
+		collect(func(name, help, kind string, value float64) {
+			writeHeader(out, name, help, kind)
+			fmt.Fprintf(out, "%s %s\n", name, formatFloat(value))
+		})
+	}
+	err := out.Flush()
+	return counter.n, err
+}
+
+// Handler отдаёт метрики для GET /metrics
+func (registry *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		registry.WriteTo(w)
+	}
+}
+
+func (f *family) write(out *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writeHeader(out, f.name, f.help, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != typeHistogram {
+			fmt.Fprintf(out, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+		for i, bound := range s.buckets {
+			labels := formatLabels(f.labels, s.labelValues, "le", formatFloat(bound))
+			fmt.Fprintf(out, "%s_bucket%s %d\n", f.name, labels, s.counts[i])
+		}
+		fmt.Fprintf(out, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(out, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(out, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+func writeHeader(out *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(out, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(out, "# TYPE %s %s\n", name, kind)
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="` + escapeLabel(values[i]) + `"`)
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName + `="` + extraValue + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"adv-mod/pkg/response"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+
+	ContextScopesKey       key = "ContextScopesKey"
+	ContextAPIKeyLookupKey key = "ContextAPIKeyLookupKey"
+)
+
+// ErrInvalidAPIKey - ключ неизвестен, отозван или его владелец удалён
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeyIdentity - от чьего имени действует ключ и что ему разрешено
+type APIKeyIdentity struct {
+	UserId uint
+	Email  string
+	Role   string
+	Scopes []string
+}
+
+// APIKeyLookup проверяет ключ. Для неизвестного ключа возвращает ErrInvalidAPIKey
+type APIKeyLookup func(key string) (*APIKeyIdentity, error)
+
+// APIKey делает проверку ключей доступной IsAuthed. Сам заголовок X-API-Key здесь
+// не проверяется: публичные маршруты не отвечают 401 из-за неверного ключа
+func APIKey(lookup APIKeyLookup) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ContextAPIKeyLookupKey, lookup)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticateAPIKey кладёт в контекст те же значения, что IsAuthed для access
+// токена, плюс scopes ключа. При ошибке пишет ответ сам и возвращает false
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, apiKey string) (*http.Request, bool) {
+	lookup, ok := r.Context().Value(ContextAPIKeyLookupKey).(APIKeyLookup)
+	if !ok {
+		response.Unauthorized(w, ErrInvalidAPIKey.Error())
+		return nil, false
+	}
+	identity, err := lookup(apiKey)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		response.Unauthorized(w, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		response.InternalServerError(w, err)
+		return nil, false
+	}
+	ctx := context.WithValue(r.Context(), ContextEmailKey, identity.Email)
+	ctx = context.WithValue(ctx, ContextUserIdKey, identity.UserId)
+	ctx = context.WithValue(ctx, ContextRoleKey, identity.Role)
+	ctx = context.WithValue(ctx, ContextScopesKey, identity.Scopes)
+	return r.WithContext(ctx), true
+}
+
+// ScopesFromContext возвращает scopes API ключа. false означает, что запрос
+// пришёл не с ключом: права access токена задаёт только роль
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(ContextScopesKey).([]string)
+	return scopes, ok
+}
+
+// RequireScope пускает API ключ только с нужным scope. Запросы с access токеном
+// проходят. Ставится внутри IsAuthed
+func RequireScope(scope string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !scopeAllowed(r.Context(), scope) {
+				response.Forbidden(w, "api key has no scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionOnly закрывает маршрут для API ключей: управлять аккаунтом и самими
+// ключами можно только после входа по паролю
+func SessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ScopesFromContext(r.Context()); ok {
+			response.Forbidden(w, "this endpoint is not available with an api key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func scopeAllowed(ctx context.Context, scope string) bool {
+	scopes, ok := ScopesFromContext(ctx)
+	if !ok {
+		return true
+	}
+	return slices.Contains(scopes, scope)
+}
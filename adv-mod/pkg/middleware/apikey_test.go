@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/rbac"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	conf := &configs.Config{
+		Auth: configs.AuthConfig{Secret: testSecret},
+	}
+	lookup := func(key string) (*middleware.APIKeyIdentity, error) {
+		switch key {
+		case "ak_links":
+			return &middleware.APIKeyIdentity{UserId: 7, Email: "a@a.ru", Role: rbac.RoleAdmin, Scopes: []string{rbac.PermissionLinksRead}}, nil
+		case "ak_broken":
+			return nil, errors.New("db is down")
+		}
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, _ := middleware.UserIdFromContext(r.Context())
+		if userId != 7 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	router := http.NewServeMux()
+	router.Handle("GET /link", middleware.IsAuthed(middleware.RequireScope(rbac.PermissionLinksRead)(ok), conf))
+	router.Handle("POST /link", middleware.IsAuthed(middleware.RequireScope(rbac.PermissionLinksWrite)(ok), conf))
+	router.Handle("GET /admin/users", middleware.IsAuthed(middleware.RequirePermission(rbac.PermissionUsersRead)(ok), conf))
+	router.Handle("GET /users/me", middleware.IsAuthed(middleware.SessionOnly(ok), conf))
+	router.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.APIKey(lookup)(router)
+
+	token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "a@a.ru", UserId: 7, Role: rbac.RoleAdmin})
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		token  string
+		status int
+	}{
+		{name: "Key with scope", method: http.MethodGet, path: "/link", key: "ak_links", status: http.StatusOK},
+		{name: "Key without scope", method: http.MethodPost, path: "/link", key: "ak_links", status: http.StatusForbidden},
+		{name: "Permission needs scope", method: http.MethodGet, path: "/admin/users", key: "ak_links", status: http.StatusForbidden},
+		{name: "Session only", method: http.MethodGet, path: "/users/me", key: "ak_links", status: http.StatusForbidden},
+		{name: "Unknown key", method: http.MethodGet, path: "/link", key: "ak_unknown", status: http.StatusUnauthorized},
+		{name: "Lookup error", method: http.MethodGet, path: "/link", key: "ak_broken", status: http.StatusInternalServerError},
+		{name: "Public route ignores unknown key", method: http.MethodGet, path: "/healthz", key: "ak_unknown", status: http.StatusOK},
+		{name: "Public route ignores lookup error", method: http.MethodGet, path: "/healthz", key: "ak_broken", status: http.StatusOK},
+		{name: "Bearer ignores scopes", method: http.MethodPost, path: "/link", token: token, status: http.StatusOK},
+		{name: "Bearer permission", method: http.MethodGet, path: "/admin/users", token: token, status: http.StatusOK},
+		{name: "Bearer session", method: http.MethodGet, path: "/users/me", token: token, status: http.StatusOK},
+		{name: "No credentials", method: http.MethodGet, path: "/link", status: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set(middleware.APIKeyHeader, tc.key)
+			}
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/response"
+	"context"
+	"net/http"
+	"strings"
+)
+
+type key string
+
+const (
+	ContextEmailKey  key = "ContextEmailKey"
+	ContextUserIdKey key = "ContextUserIdKey"
+	ContextRoleKey   key = "ContextRoleKey"
+)
+
+// IsAuthed пропускает запрос дальше только с валидным заголовком Authorization: Bearer <token>
+// или с API ключом в X-API-Key. Ключи проверяет lookup, переданный middleware APIKey
+func IsAuthed(next http.Handler, config *configs.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+			r, ok := authenticateAPIKey(w, r, apiKey)
+			if ok {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+		authHeader := r.Header.Get("Authorization")
+		token, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok || token == "" {
+			response.Unauthorized(w, "missing bearer token")
+			return
+		}
+		data, err := jwt.NewJWT(config.Auth.Secret).Parse(token)
+		if err != nil {
+			response.Unauthorized(w, err.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), ContextEmailKey, data.Email)
+		ctx = context.WithValue(ctx, ContextUserIdKey, data.UserId)
+		ctx = context.WithValue(ctx, ContextRoleKey, data.Role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// EmailFromContext возвращает email пользователя, прошедшего IsAuthed
+func EmailFromContext(ctx context.Context) (string, bool) {
+	email, ok := ctx.Value(ContextEmailKey).(string)
+	return email, ok
+}
+
+// UserIdFromContext возвращает id пользователя. Токены, выпущенные до появления
+// uid в claims, id не содержат
+func UserIdFromContext(ctx context.Context) (uint, bool) {
+	userId, ok := ctx.Value(ContextUserIdKey).(uint)
+	return userId, ok && userId != 0
+}
+
+// RequireUserId берёт id пользователя из контекста. Если его нет, отвечает 401
+// сам: клиент с токеном без uid должен войти заново
+func RequireUserId(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	userId, ok := UserIdFromContext(r.Context())
+	if !ok {
+		response.Unauthorized(w, "token has no user id, log in again")
+	}
+	return userId, ok
+}
+
+// RoleFromContext возвращает роль из токена, прошедшего IsAuthed
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(ContextRoleKey).(string)
+	return role, ok
+}
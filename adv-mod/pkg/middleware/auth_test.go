@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "secret"
+
+func newAuthedHandler() http.Handler {
+	conf := &configs.Config{
+		Auth: configs.AuthConfig{Secret: testSecret},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := middleware.EmailFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(email))
+	})
+	return middleware.IsAuthed(next, conf)
+}
+
+func TestIsAuthed(t *testing.T) {
+	token, err := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "a@a.ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	newAuthedHandler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "a@a.ru" {
+		t.Errorf("Expected email in context, got %q", w.Body.String())
+	}
+}
+
+func TestIsAuthedRejected(t *testing.T) {
+	wrongSecret, _ := jwt.NewJWT("other").Create(jwt.JWTData{Email: "a@a.ru"})
+	expired, _ := (&jwt.JWT{Secret: testSecret, TTL: -time.Minute}).Create(jwt.JWTData{Email: "a@a.ru"})
+
+	testCases := []struct {
+		name   string
+		header string
+	}{
+		{name: "Missing header", header: ""},
+		{name: "Not bearer", header: "Basic YTpi"},
+		{name: "Empty token", header: "Bearer "},
+		{name: "Garbage token", header: "Bearer abc.def.ghi"},
+		{name: "Wrong secret", header: "Bearer " + wrongSecret},
+		{name: "Expired", header: "Bearer " + expired},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			newAuthedHandler().ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireUserId(t *testing.T) {
+	testCases := []struct {
+		name   string
+		userId uint
+		status int
+	}{
+		{name: "With user id", userId: 7, status: http.StatusOK},
+		{name: "Old token without user id", status: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "a@a.ru", UserId: tc.userId})
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userId, ok := middleware.RequireUserId(w, r)
+				if !ok {
+					return
+				}
+				if userId != tc.userId {
+					t.Errorf("Expected user id %d, got %d", tc.userId, userId)
+				}
+			})
+			conf := &configs.Config{Auth: configs.AuthConfig{Secret: testSecret}}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			middleware.IsAuthed(next, conf).ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware_test
+
+import (
+	"adv-mod/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := middleware.Chain(mark("first"), mark("second"), mark("third"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handler")
+		}),
+	)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	expected := []string{"first", "second", "third", "handler"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestWrapperWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	wrapper := middleware.NewWrapperWriter(w)
+	wrapper.WriteHeader(http.StatusTeapot)
+	wrapper.WriteHeader(http.StatusOK)
+	wrapper.Write([]byte("hello"))
+
+	if wrapper.StatusCode != http.StatusTeapot || w.Code != http.StatusTeapot {
+		t.Errorf("Expected %d, got %d/%d", http.StatusTeapot, wrapper.StatusCode, w.Code)
+	}
+	if wrapper.Bytes != 5 {
+		t.Errorf("Expected 5 bytes, got %d", wrapper.Bytes)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+func DefaultCORSOptions() CORSOptions {
+	return CORSOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		AllowedHeaders: []string{"Authorization", "Content-Type", RequestIDHeader, APIKeyHeader},
+		ExposedHeaders: []string{RequestIDHeader},
+		MaxAge:         600,
+	}
+}
+
+func CORS(options CORSOptions) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			if !options.isOriginAllowed(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// С credentials браузер не принимает "*", поэтому отражаем origin
+			if slices.Contains(options.AllowedOrigins, "*") && !options.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if options.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !isPreflight {
+				if len(options.ExposedHeaders) > 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(options.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", strings.Join(options.AllowedMethods, ", "))
+			if len(options.AllowedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(options.AllowedHeaders, ", "))
+			}
+			if options.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(options.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func (options CORSOptions) isOriginAllowed(origin string) bool {
+	for _, allowed := range options.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"adv-mod/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCORSHandler(options middleware.CORSOptions) http.Handler {
+	return middleware.CORS(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	options := middleware.DefaultCORSOptions()
+	options.AllowedOrigins = []string{"https://app.example.com"}
+	req := httptest.NewRequest(http.MethodOptions, "/auth/login", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	newCORSHandler(options).ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Unexpected Allow-Origin %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Error("Allow-Methods is missing")
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	options := middleware.DefaultCORSOptions()
+	options.AllowedOrigins = []string{"https://app.example.com"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	newCORSHandler(options).ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin, got %q", got)
+	}
+}
+
+func TestCORSCredentialsReflectOrigin(t *testing.T) {
+	options := middleware.DefaultCORSOptions()
+	options.AllowCredentials = true
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	newCORSHandler(options).ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected reflected origin, got %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("Allow-Credentials is missing")
+	}
+}
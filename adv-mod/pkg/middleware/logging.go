@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrapper := NewWrapperWriter(w)
+		next.ServeHTTP(wrapper, r)
+		requestId, _ := RequestIDFromContext(r.Context())
+		slog.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", wrapper.StatusCode,
+			"latency", time.Since(start),
+			"bytes", wrapper.Bytes,
+			"request_id", requestId,
+		)
+	})
+}
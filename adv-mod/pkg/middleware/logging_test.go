@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"adv-mod/pkg/middleware"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	handler := middleware.Chain(middleware.RequestID, middleware.Logging)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		}),
+	)
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", nil)
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Log entry is not JSON: %v: %s", err, buf.String())
+	}
+	expected := map[string]any{
+		"method":     "POST",
+		"path":       "/auth/register",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(len("created")),
+		"request_id": "abc-123",
+	}
+	for field, value := range expected {
+		if entry[field] != value {
+			t.Errorf("Expected %s=%v, got %v", field, value, entry[field])
+		}
+	}
+	if _, ok := entry["latency"]; !ok {
+		t.Error("Latency is not logged")
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"adv-mod/pkg/metrics"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unmatchedRoute - метка для запросов, не совпавших ни с одним шаблоном, чтобы
+// произвольные пути не раздували число серий
+const unmatchedRoute = "unmatched"
+
+// Metrics считает запросы, их длительность и запросы в обработке. Шаблон маршрута
+// ServeMux записывает в r.Pattern, поэтому middleware должен оборачивать роутер
+// напрямую: запрос, пересозданный через WithContext, шаблона не получит
+func Metrics(registry *metrics.Registry) Middleware {
+	requests := registry.NewCounter("http_requests_total", "HTTP requests by route, method and status.", "route", "method", "status")
+	duration := registry.NewHistogram("http_request_duration_seconds", "HTTP request latency by route, method and status.", nil, "route", "method", "status")
+	inFlight := registry.NewGauge("http_requests_in_flight", "HTTP requests currently being served.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			wrapper := NewWrapperWriter(w)
+			defer func() {
+				inFlight.Dec()
+				status := wrapper.StatusCode
+				// Паника до записи ответа станет 500 в Recovery
+				if recovered := recover(); recovered != nil {
+					if !wrapper.WroteHeader() {
+						status = http.StatusInternalServerError
+					}
+					defer panic(recovered)
+				}
+				labels := []string{route(r), r.Method, strconv.Itoa(status)}
+				requests.Inc(labels...)
+				duration.Observe(time.Since(start).Seconds(), labels...)
+			}()
+			next.ServeHTTP(wrapper, r)
+		})
+	}
+}
+
+// route возвращает путь из шаблона: "POST /auth/login" → "/auth/login"
+func route(r *http.Request) string {
+	if r.Pattern == "" {
+		return unmatchedRoute
+	}
+	_, path, ok := strings.Cut(r.Pattern, " ")
+	if !ok {
+		return r.Pattern
+	}
+	return path
+}
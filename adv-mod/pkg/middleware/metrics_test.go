@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"adv-mod/pkg/metrics"
+	"adv-mod/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	router := http.NewServeMux()
+	router.HandleFunc("GET /links/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := middleware.Chain(middleware.Recovery, middleware.Metrics(registry))(router)
+
+	for _, path := range []string{"/links/1", "/links/2", "/missing/1", "/missing/2", "/panic"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`http_requests_total{route="/links/{id}",method="GET",status="204"} 2`,
+		`http_requests_total{route="unmatched",method="GET",status="404"} 2`,
+		`http_requests_total{route="/panic",method="GET",status="500"} 1`,
+		`http_request_duration_seconds_count{route="/links/{id}",method="GET",status="204"} 2`,
+		`http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"adv-mod/pkg/ratelimit"
+	"adv-mod/pkg/response"
+	"net"
+	"net/http"
+)
+
+type KeyFunc func(r *http.Request) string
+
+// RateLimit ограничивает запросы по ключу из keyFunc, отвечая 429 с Retry-After
+func RateLimit(store ratelimit.Store, limit ratelimit.Limit, keyFunc KeyFunc) Middleware {
+	return func(next http.Handler) http.Handler {
+		if limit.Disabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(keyFunc(r), limit)
+			if err != nil {
+				response.InternalServerError(w, err)
+				return
+			}
+			if !result.Allowed {
+				response.TooManyRequests(w, result.RetryAfter, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP берёт адрес из соединения. X-Forwarded-For намеренно не читается:
+// без доверенного прокси его подделывает любой клиент
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByClientIP строит ключ вида "<prefix>:ip:<адрес>", чтобы у разных маршрутов были свои вёдра
+func ByClientIP(prefix string) KeyFunc {
+	return func(r *http.Request) string {
+		return prefix + ":ip:" + ClientIP(r)
+	}
+}
@@ -0,0 +1,39 @@
+package middleware_test
+
+import (
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+	handler := middleware.RateLimit(ratelimit.NewMemoryStore(), limit, middleware.ByClientIP("test"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	send("10.0.0.1:1000")
+	send("10.0.0.1:1001")
+	w := send("10.0.0.1:1002")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+	if w := send("10.0.0.2:1000"); w.Code != http.StatusOK {
+		t.Errorf("Other IP must not be limited, got %d", w.Code)
+	}
+}
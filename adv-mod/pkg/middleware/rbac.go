@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"adv-mod/pkg/rbac"
+	"adv-mod/pkg/response"
+	"net/http"
+	"slices"
+)
+
+// RequireRole пропускает только перечисленные роли. Ставится внутри IsAuthed:
+//
+//	router.Handle("GET /admin/users", middleware.IsAuthed(middleware.RequireRole(rbac.RoleAdmin)(handler.List()), conf))
+func RequireRole(roles ...string) Middleware {
+	return require(func(role string) bool {
+		return slices.Contains(roles, role)
+	}, "")
+}
+
+// RequirePermission пропускает роли, которым выдано разрешение в rbac.
+// API ключу, кроме роли владельца, нужен scope с тем же именем
+func RequirePermission(permission string) Middleware {
+	return require(func(role string) bool {
+		return rbac.HasPermission(role, permission)
+	}, permission)
+}
+
+// require с непустым scope дополнительно проверяет scopes API ключа
+func require(allowed func(role string) bool, scope string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := RoleFromContext(r.Context())
+			if !ok {
+				response.Unauthorized(w, "authentication required")
+				return
+			}
+			if !allowed(role) || (scope != "" && !scopeAllowed(r.Context(), scope)) {
+				response.Forbidden(w, "insufficient permissions")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"adv-mod/configs"
+	"adv-mod/pkg/jwt"
+	"adv-mod/pkg/middleware"
+	"adv-mod/pkg/rbac"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRoleAndPermission(t *testing.T) {
+	conf := &configs.Config{
+		Auth: configs.AuthConfig{Secret: testSecret},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router := http.NewServeMux()
+	router.Handle("GET /admin/users", middleware.IsAuthed(middleware.RequireRole(rbac.RoleAdmin)(ok), conf))
+	router.Handle("PATCH /admin/users/{id}/role", middleware.IsAuthed(middleware.RequirePermission(rbac.PermissionUsersWrite)(ok), conf))
+
+	tokenFor := func(role string) string {
+		token, _ := jwt.NewJWT(testSecret).Create(jwt.JWTData{Email: "a@a.ru", Role: role})
+		return token
+	}
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{name: "Admin role", method: http.MethodGet, path: "/admin/users", token: tokenFor(rbac.RoleAdmin), status: http.StatusOK},
+		{name: "User role", method: http.MethodGet, path: "/admin/users", token: tokenFor(rbac.RoleUser), status: http.StatusForbidden},
+		{name: "Admin permission", method: http.MethodPatch, path: "/admin/users/1/role", token: tokenFor(rbac.RoleAdmin), status: http.StatusOK},
+		{name: "User permission", method: http.MethodPatch, path: "/admin/users/1/role", token: tokenFor(rbac.RoleUser), status: http.StatusForbidden},
+		{name: "No token", method: http.MethodGet, path: "/admin/users", status: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireRoleWithoutIsAuthed(t *testing.T) {
+	handler := middleware.RequireRole(rbac.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"adv-mod/pkg/response"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recovery перехватывает панику обработчика и отвечает 500 вместо обрыва соединения
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapper := NewWrapperWriter(w)
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			requestId, _ := RequestIDFromContext(r.Context())
+			slog.Error("panic",
+				"error", err,
+				"request_id", requestId,
+				"stack", string(debug.Stack()),
+			)
+			if !wrapper.WroteHeader() {
+				response.Error(wrapper, http.StatusInternalServerError, response.CodeInternal, http.StatusText(http.StatusInternalServerError), nil)
+			}
+		}()
+		next.ServeHTTP(wrapper, r)
+	})
+}
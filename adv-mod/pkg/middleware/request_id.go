@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	RequestIDHeader         = "X-Request-ID"
+	ContextRequestIdKey key = "ContextRequestIdKey"
+	maxRequestIdLength      = 128
+)
+
+// RequestID берёт X-Request-ID из запроса или генерирует новый и возвращает его в ответе
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIDHeader)
+		if !isValidRequestId(requestId) {
+			requestId = newRequestId()
+		}
+		w.Header().Set(RequestIDHeader, requestId)
+		ctx := context.WithValue(r.Context(), ContextRequestIdKey, requestId)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestId, ok := ctx.Value(ContextRequestIdKey).(string)
+	return requestId, ok
+}
+
+// Чужой идентификатор попадает в логи, поэтому пропускаем только безопасные символы
+func isValidRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range id {
+		isAllowed := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+		if !isAllowed {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
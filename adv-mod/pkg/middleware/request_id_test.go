@@ -0,0 +1,46 @@
+package middleware_test
+
+import (
+	"adv-mod/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var fromContext string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext, _ = middleware.RequestIDFromContext(r.Context())
+	}))
+
+	testCases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "Propagated", incoming: "abc-123", keep: true},
+		{name: "Generated", incoming: "", keep: false},
+		{name: "Unsafe replaced", incoming: "abc\nINJECTED", keep: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(middleware.RequestIDHeader, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			got := w.Header().Get(middleware.RequestIDHeader)
+			if got == "" || got != fromContext {
+				t.Fatalf("Header %q and context %q differ", got, fromContext)
+			}
+			if tc.keep && got != tc.incoming {
+				t.Errorf("Expected %q, got %q", tc.incoming, got)
+			}
+			if !tc.keep && got == tc.incoming {
+				t.Errorf("Expected new id, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import "net/http"
+
+// WrapperWriter запоминает статус и размер ответа для логирования
+type WrapperWriter struct {
+	http.ResponseWriter
+	StatusCode  int
+	Bytes       int
+	wroteHeader bool
+}
+
+func NewWrapperWriter(w http.ResponseWriter) *WrapperWriter {
+	return &WrapperWriter{
+		ResponseWriter: w,
+		StatusCode:     http.StatusOK,
+	}
+}
+
+func (w *WrapperWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.StatusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *WrapperWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += n
+	return n, err
+}
+
+// WroteHeader сообщает, начал ли обработчик писать ответ
+func (w *WrapperWriter) WroteHeader() bool {
+	return w.wroteHeader
+}
+
+// Unwrap нужен http.ResponseController, чтобы добраться до Flush и дедлайнов
+func (w *WrapperWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
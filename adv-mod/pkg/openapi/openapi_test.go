@@ -0,0 +1,189 @@
+package openapi_test
+
+import (
+	"adv-mod/pkg/openapi"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Audit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type CreateRequest struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required,password"`
+	Role     string   `json:"role" validate:"omitempty,oneof=user admin"`
+	Age      int      `json:"age" validate:"gte=18,lte=120"`
+	Tags     []string `json:"tags" validate:"max=5,dive,min=2"`
+	Internal string   `json:"-"`
+}
+
+type ItemResponse struct {
+	Audit
+	Id        uint          `json:"id"`
+	Parent    *ItemResponse `json:"parent,omitempty"`
+	DeletedAt *time.Time    `json:"deleted_at"`
+}
+
+func newTestRegistry() (*openapi.Registry, *http.ServeMux) {
+	registry := openapi.NewRegistry("test", "1.0.0")
+	router := http.NewServeMux()
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	registry.Handle(router, "POST /items", noop, openapi.Operation{
+		Summary:   "Create item",
+		Request:   CreateRequest{},
+		Responses: map[int]any{201: ItemResponse{}, 204: nil},
+	})
+	registry.Handle(router, "GET /items/{id}", noop, openapi.Operation{
+		Query:     []openapi.Parameter{{Name: "expand", Type: "boolean"}},
+		Responses: map[int]any{200: ItemResponse{}},
+		Security:  []string{openapi.SecurityBearer},
+	})
+	return registry, router
+}
+
+func TestDocumentSchemas(t *testing.T) {
+	registry, _ := newTestRegistry()
+	doc := registry.Document()
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got %s", doc.OpenAPI)
+	}
+
+	create := doc.Components.Schemas["CreateRequest"]
+	if create == nil {
+		t.Fatal("Expected CreateRequest schema")
+	}
+	if !slices.Equal(create.Required, []string{"email", "password"}) {
+		t.Errorf("Expected email and password required, got %v", create.Required)
+	}
+	if create.Properties["email"].Format != "email" {
+		t.Errorf("Expected email format, got %+v", create.Properties["email"])
+	}
+	if minLength := create.Properties["password"].MinLength; minLength == nil || *minLength != 8 {
+		t.Errorf("Expected password minLength 8, got %v", minLength)
+	}
+	if enum := create.Properties["role"].Enum; len(enum) != 2 || enum[0] != "user" {
+		t.Errorf("Expected role enum, got %v", enum)
+	}
+	age := create.Properties["age"]
+	if age.Minimum == nil || *age.Minimum != 18 || age.Maximum == nil || *age.Maximum != 120 {
+		t.Errorf("Expected age bounds, got %+v", age)
+	}
+	tags := create.Properties["tags"]
+	if tags.MaxItems == nil || *tags.MaxItems != 5 || tags.Items.MinLength != nil {
+		t.Errorf("Expected maxItems and no rules after dive, got %+v", tags)
+	}
+	if _, ok := create.Properties["Internal"]; ok {
+		t.Error("Expected json:\"-\" field to be skipped")
+	}
+
+	item := doc.Components.Schemas["ItemResponse"]
+	if item == nil {
+		t.Fatal("Expected ItemResponse schema")
+	}
+	if _, ok := item.Properties["created_by"]; !ok {
+		t.Error("Expected embedded fields to be flattened")
+	}
+	if item.Properties["parent"].Ref != "#/components/schemas/ItemResponse" {
+		t.Errorf("Expected recursive $ref, got %+v", item.Properties["parent"])
+	}
+	if types, ok := item.Properties["deleted_at"].Type.([]string); !ok || !slices.Contains(types, "null") {
+		t.Errorf("Expected nullable date-time, got %+v", item.Properties["deleted_at"])
+	}
+}
+
+func TestDocumentOperations(t *testing.T) {
+	registry, _ := newTestRegistry()
+	doc := registry.Document()
+
+	create := doc.Paths["/items"]["post"]
+	if create == nil || create.RequestBody == nil {
+		t.Fatal("Expected POST /items with request body")
+	}
+	if create.Responses["204"] == nil || create.Responses["204"].Content != nil {
+		t.Errorf("Expected empty 204 response, got %+v", create.Responses["204"])
+	}
+
+	get := doc.Paths["/items/{id}"]["get"]
+	if get == nil {
+		t.Fatal("Expected GET /items/{id}")
+	}
+	if get.OperationId != "get_items_id" {
+		t.Errorf("Unexpected operationId %q", get.OperationId)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].In != "path" || !get.Parameters[0].Required || get.Parameters[1].In != "query" {
+		t.Errorf("Expected path and query parameters, got %+v", get.Parameters)
+	}
+	if len(get.Security) != 1 {
+		t.Errorf("Expected bearer security, got %v", get.Security)
+	}
+	if doc.Components.SecuritySchemes[openapi.SecurityBearer] == nil {
+		t.Error("Expected bearer security scheme")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		swaggerUI bool
+		status    int
+	}{
+		{name: "With Swagger UI", swaggerUI: true, status: http.StatusOK},
+		{name: "Without Swagger UI", swaggerUI: false, status: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry, router := newTestRegistry()
+			registry.Routes(router, tc.swaggerUI)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+			var doc map[string]any
+			if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc["openapi"] != "3.1.0" {
+				t.Errorf("Expected OpenAPI document, got %v", doc["openapi"])
+			}
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+			if w.Code != tc.status {
+				t.Errorf("Expected %d for /docs, got %d", tc.status, w.Code)
+			}
+			if tc.swaggerUI {
+				if policy := w.Header().Get("Content-Security-Policy"); !strings.Contains(policy, "swagger-ui-dist@5.17.14/") {
+					t.Errorf("Expected CSP with pinned Swagger UI, got %q", policy)
+				}
+				if strings.Contains(w.Body.String(), "swagger-ui-dist@5/") {
+					t.Error("Expected exact Swagger UI version")
+				}
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/init.js", nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected 200 for /docs/init.js, got %d", w.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestNilRegistryRegistersRoutes(t *testing.T) {
+	var registry *openapi.Registry
+	router := http.NewServeMux()
+	registry.Handle(router, "GET /ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), openapi.Operation{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("Expected route to be registered, got %d", w.Code)
+	}
+}
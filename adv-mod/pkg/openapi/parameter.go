@@ -0,0 +1,23 @@
+package openapi
+
+// Parameter - параметр query string
+type Parameter struct {
+	Name        string
+	Description string
+	Type        string
+	Required    bool
+}
+
+func (parameter Parameter) object() *ParameterObject {
+	schemaType := parameter.Type
+	if schemaType == "" {
+		schemaType = "string"
+	}
+	return &ParameterObject{
+		Name:        parameter.Name,
+		In:          "query",
+		Description: parameter.Description,
+		Required:    parameter.Required,
+		Schema:      &Schema{Type: schemaType},
+	}
+}
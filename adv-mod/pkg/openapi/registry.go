@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"adv-mod/pkg/response"
+	_ "embed"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	SecurityBearer = "bearerAuth"
+	SecurityAPIKey = "apiKeyAuth"
+	jsonType       = "application/json"
+)
+
+// swaggerAssets - Swagger UI с точно закреплённой версией. CSP страницы /docs
+// разрешает скрипты и стили только из этого каталога и с нашего origin
+const swaggerAssets = "https://unpkg.com/swagger-ui-dist@5.17.14/"
+
+var swaggerPolicy = "default-src 'none'; script-src 'self' " + swaggerAssets +
+	"; style-src " + swaggerAssets + "; img-src 'self' data:; connect-src 'self'"
+
+var (
+	//go:embed swagger.html
+	swaggerPage []byte
+	//go:embed swagger-init.js
+	swaggerInit []byte
+)
+
+var pathParam = regexp.MustCompile(`\{([A-Za-z0-9_]+)(?:\.\.\.)?\}`)
+
+// Operation описывает маршрут. Request и значения Responses - нулевые значения
+// payload типов, nil в Responses означает ответ без тела
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     any
+	Responses   map[int]any
+	Query       []Parameter
+	Security    []string
+}
+
+type route struct {
+	method    string
+	path      string
+	operation Operation
+}
+
+// Registry собирает описания маршрутов. Методы безопасно вызывать на nil:
+// тогда маршруты только регистрируются в роутере
+type Registry struct {
+	Title   string
+	Version string
+
+	mu              sync.Mutex
+	routes          []route
+	securitySchemes map[string]*SecurityScheme
+}
+
+func NewRegistry(title, version string) *Registry {
+	return &Registry{
+		Title:   title,
+		Version: version,
+		securitySchemes: map[string]*SecurityScheme{
+			SecurityBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			SecurityAPIKey: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		},
+	}
+}
+
+// Handle регистрирует handler в router по шаблону ServeMux "METHOD /path" и описывает его
+func (registry *Registry) Handle(router *http.ServeMux, pattern string, handler http.Handler, operation Operation) {
+	router.Handle(pattern, handler)
+	registry.Describe(pattern, operation)
+}
+
+// Describe добавляет описание маршрута, зарегистрированного иначе
+func (registry *Registry) Describe(pattern string, operation Operation) {
+	if registry == nil {
+		return
+	}
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.routes = append(registry.routes, route{
+		method:    strings.ToLower(strings.TrimSpace(method)),
+		path:      strings.TrimSpace(path),
+		operation: operation,
+	})
+}
+
+// AddSecurityScheme объявляет схему авторизации, на которую ссылается Operation.Security
+func (registry *Registry) AddSecurityScheme(name string, scheme SecurityScheme) {
+	if registry == nil {
+		return
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.securitySchemes[name] = &scheme
+}
+
+// Document строит спецификацию по зарегистрированным маршрутам
+func (registry *Registry) Document() *Document {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: registry.Title, Version: registry.Version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			SecuritySchemes: maps.Clone(registry.securitySchemes),
+		},
+	}
+	for _, r := range registry.routes {
+		item, ok := doc.Paths[r.path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[r.path] = item
+		}
+		// Шаблон без метода в ServeMux подходит для любого метода, описываем как GET
+		method := r.method
+		if method == "" {
+			method = "get"
+		}
+		item[method] = g.operation(method, r.path, r.operation)
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func (g *generator) operation(method, path string, operation Operation) *OperationObject {
+	object := &OperationObject{
+		OperationId: operationId(method, path),
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		Tags:        operation.Tags,
+		Responses:   map[string]*Response{},
+	}
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		object.Parameters = append(object.Parameters, &ParameterObject{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, query := range operation.Query {
+		object.Parameters = append(object.Parameters, query.object())
+	}
+	if operation.Request != nil {
+		object.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				jsonType: {Schema: g.schemaFor(reflect.TypeOf(operation.Request))},
+			},
+		}
+	}
+	statuses := make([]int, 0, len(operation.Responses))
+	for status := range operation.Responses {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	for _, status := range statuses {
+		resp := &Response{Description: http.StatusText(status)}
+		if body := operation.Responses[status]; body != nil {
+			resp.Content = map[string]*MediaType{
+				jsonType: {Schema: g.schemaFor(reflect.TypeOf(body))},
+			}
+		}
+		object.Responses[strconv.Itoa(status)] = resp
+	}
+	for _, name := range operation.Security {
+		object.Security = append(object.Security, map[string][]string{name: {}})
+	}
+	return object
+}
+
+// operationId: "GET /admin/users/{id}/role" → "get_admin_users_id_role"
+func operationId(method, path string) string {
+	parts := []string{method}
+	for part := range strings.SplitSeq(path, "/") {
+		part = strings.Trim(part, "{}.")
+		if part != "" {
+			parts = append(parts, nonIdentifier.ReplaceAllString(part, "_"))
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// Routes публикует GET /openapi.json и, если swaggerUI, страницу GET /docs
+// со скриптом инициализации GET /docs/init.js
+func (registry *Registry) Routes(router *http.ServeMux, swaggerUI bool) {
+	router.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		response.Json(w, registry.Document(), http.StatusOK)
+	})
+	if !swaggerUI {
+		return
+	}
+	router.HandleFunc("GET /docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", swaggerPolicy)
+		w.Write(swaggerPage)
+	})
+	router.HandleFunc("GET /docs/init.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Write(swaggerInit)
+	})
+}
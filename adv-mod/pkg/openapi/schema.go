@@ -0,0 +1,281 @@
+package openapi
+
+import (
+	"adv-mod/pkg/request"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule дополняет схему поля по правилу validate с параметром param
+type Rule func(schema *Schema, kind reflect.Kind, param string)
+
+var marshaler = reflect.TypeFor[json.Marshaler]()
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+var rules = map[string]Rule{
+	"email":    format("email"),
+	"url":      format("uri"),
+	"http_url": format("uri"),
+	"uri":      format("uri"),
+	"uuid":     format("uuid"),
+	"uuid4":    format("uuid"),
+	"datetime": format("date-time"),
+	"ip":       format("ip"),
+	"ipv4":     format("ipv4"),
+	"ipv6":     format("ipv6"),
+	"hostname": format("hostname"),
+	"password": func(schema *Schema, kind reflect.Kind, param string) {
+		schema.MinLength = ptr(request.PasswordMinLength)
+		schema.Description = "at least one uppercase letter, one lowercase letter and one digit"
+	},
+	"phone": func(schema *Schema, kind reflect.Kind, param string) {
+		schema.Pattern = request.PhonePattern
+	},
+	"slug": func(schema *Schema, kind reflect.Kind, param string) {
+		schema.Pattern = request.SlugPattern
+	},
+	"min": minRule,
+	"gte": minRule,
+	"max": maxRule,
+	"lte": maxRule,
+	"gt":  bound(func(s *Schema, n float64) { s.ExclusiveMinimum = &n }, nil, nil),
+	"lt":  bound(func(s *Schema, n float64) { s.ExclusiveMaximum = &n }, nil, nil),
+	"len": func(schema *Schema, kind reflect.Kind, param string) {
+		minRule(schema, kind, param)
+		maxRule(schema, kind, param)
+	},
+	"oneof": func(schema *Schema, kind reflect.Kind, param string) {
+		for value := range strings.FieldsSeq(param) {
+			if isNumber(kind) {
+				n, err := strconv.ParseFloat(value, 64)
+				if err == nil {
+					schema.Enum = append(schema.Enum, n)
+				}
+				continue
+			}
+			schema.Enum = append(schema.Enum, value)
+		}
+	},
+}
+
+var (
+	minRule = bound(func(s *Schema, n float64) { s.Minimum = &n }, func(s *Schema, n int) { s.MinLength = &n }, func(s *Schema, n int) { s.MinItems = &n })
+	maxRule = bound(func(s *Schema, n float64) { s.Maximum = &n }, func(s *Schema, n int) { s.MaxLength = &n }, func(s *Schema, n int) { s.MaxItems = &n })
+)
+
+// RegisterRule описывает в схеме собственное правило validate, зарегистрированное
+// через request.RegisterValidation. Вызывать при старте, до генерации документа
+func RegisterRule(tag string, rule Rule) {
+	rules[tag] = rule
+}
+
+func format(name string) Rule {
+	return func(schema *Schema, kind reflect.Kind, param string) {
+		schema.Format = name
+	}
+}
+
+// bound выбирает ограничение по виду поля: число, длина строки или размер списка
+func bound(number func(*Schema, float64), length func(*Schema, int), items func(*Schema, int)) Rule {
+	return func(schema *Schema, kind reflect.Kind, param string) {
+		switch {
+		case isNumber(kind) && number != nil:
+			n, err := strconv.ParseFloat(param, 64)
+			if err == nil {
+				number(schema, n)
+			}
+		case kind == reflect.String && length != nil:
+			n, err := strconv.Atoi(param)
+			if err == nil {
+				length(schema, n)
+			}
+		case (kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map) && items != nil:
+			n, err := strconv.Atoi(param)
+			if err == nil {
+				items(schema, n)
+			}
+		}
+	}
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// generator строит схемы и складывает именованные структуры в components
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+	schema := g.inline(t)
+	if typeName, ok := schema.Type.(string); ok && nullable {
+		schema.Type = []string{typeName, "null"}
+	}
+	return schema
+}
+
+func (g *generator) inline(t reflect.Type) *Schema {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return &Schema{Type: "string", Format: "date-time"}
+	case reflect.TypeFor[time.Duration]():
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	}
+	// Собственный формат JSON по типу не угадать
+	if t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler) {
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.register(t)}
+	}
+	// interface{} и прочее описываем пустой схемой - допустимо любое значение
+	return &Schema{}
+}
+
+func (g *generator) register(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := nonIdentifier.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = nonIdentifier.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "_" + name
+	}
+	g.names[t] = name
+	// Заглушка до обхода полей защищает от бесконечной рекурсии
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+	return name
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(t, schema)
+	return schema
+}
+
+func (g *generator) fields(t reflect.Type, schema *Schema) {
+	for field := range fieldsOf(t) {
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.fields(embedded, schema)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := g.schemaFor(field.Type)
+		required := applyRules(property, field)
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+func fieldsOf(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// jsonName повторяет правила encoding/json: "-" пропускает поле, пустое имя берёт имя поля
+func jsonName(field reflect.StructField) (name string, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	return name, true
+}
+
+// applyRules переносит правила validate в схему и сообщает, обязательно ли поле.
+// Правила после dive относятся к элементам и не разбираются
+func applyRules(schema *Schema, field reflect.StructField) (required bool) {
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		return false
+	}
+	t := field.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			break
+		}
+		if name == "required" {
+			required = true
+			continue
+		}
+		if apply, ok := rules[name]; ok && schema.Ref == "" {
+			apply(schema, kind, param)
+		}
+	}
+	return required
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const pruneEvery = 1024
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	per    time.Duration
+}
+
+type lockout struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+	resetAfter  time.Duration
+}
+
+// MemoryStore реализует Store и LockoutStore в памяти процесса
+type MemoryStore struct {
+	Now func() time.Time
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	lockouts map[string]*lockout
+	ops      int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now:      time.Now,
+		buckets:  map[string]*bucket{},
+		lockouts: map[string]*lockout{},
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (Result, error) {
+	if limit.Disabled() {
+		return Result{Allowed: true}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	s.maybePrune(now)
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.per = limit.Per
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true}, nil
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return Result{Allowed: false, RetryAfter: wait}, nil
+}
+
+func (s *MemoryStore) LockedFor(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.lockouts[key]
+	if !ok {
+		return 0, nil
+	}
+	remaining := l.lockedUntil.Sub(s.Now())
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (s *MemoryStore) RegisterFailure(key string, policy LockoutPolicy) (time.Duration, error) {
+	if policy.Disabled() {
+		return 0, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	s.maybePrune(now)
+
+	l, ok := s.lockouts[key]
+	if !ok || (policy.MaxDuration > 0 && now.Sub(l.lastFailure) > policy.MaxDuration) {
+		l = &lockout{}
+		s.lockouts[key] = l
+	}
+	l.lastFailure = now
+	l.resetAfter = policy.MaxDuration
+	l.failures++
+	if l.failures < policy.MaxFailures {
+		return 0, nil
+	}
+	l.failures = 0
+	l.lockouts++
+	lockedFor := policy.duration(l.lockouts)
+	l.lockedUntil = now.Add(lockedFor)
+	return lockedFor, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lockouts, key)
+	return nil
+}
+
+// maybePrune время от времени удаляет полные вёдра и забытые блокировки,
+// чтобы перебор IP не раздувал память
+func (s *MemoryStore) maybePrune(now time.Time) {
+	s.ops++
+	if s.ops%pruneEvery != 0 {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.per {
+			delete(s.buckets, key)
+		}
+	}
+	for key, l := range s.lockouts {
+		if now.After(l.lockedUntil) && now.Sub(l.lastFailure) > l.resetAfter {
+			delete(s.lockouts, key)
+		}
+	}
+}
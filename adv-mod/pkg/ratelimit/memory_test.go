@@ -0,0 +1,98 @@
+package ratelimit_test
+
+import (
+	"adv-mod/pkg/ratelimit"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*ratelimit.MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := ratelimit.NewMemoryStore()
+	store.Now = clock.Now
+	return store, clock
+}
+
+func TestTakeTokenBucket(t *testing.T) {
+	store, clock := newTestStore()
+	limit := ratelimit.Limit{Requests: 3, Per: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if res, _ := store.Take("ip:1", limit); !res.Allowed {
+			t.Fatalf("Request %d must be allowed", i+1)
+		}
+	}
+	res, _ := store.Take("ip:1", limit)
+	if res.Allowed {
+		t.Fatal("Fourth request must be limited")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > 20*time.Second {
+		t.Errorf("Unexpected retry after %v", res.RetryAfter)
+	}
+	if res, _ := store.Take("ip:2", limit); !res.Allowed {
+		t.Error("Keys must not share a bucket")
+	}
+
+	clock.now = clock.now.Add(20 * time.Second)
+	if res, _ := store.Take("ip:1", limit); !res.Allowed {
+		t.Error("Bucket must refill one token in 20s")
+	}
+}
+
+func TestTakeDisabled(t *testing.T) {
+	store, _ := newTestStore()
+	for i := 0; i < 100; i++ {
+		if res, _ := store.Take("ip:1", ratelimit.Limit{}); !res.Allowed {
+			t.Fatal("Zero limit must not restrict")
+		}
+	}
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	store, clock := newTestStore()
+	policy := ratelimit.LockoutPolicy{
+		MaxFailures:  3,
+		BaseDuration: time.Minute,
+		MaxDuration:  3 * time.Minute,
+	}
+	fail := func(times int) time.Duration {
+		var lockedFor time.Duration
+		for i := 0; i < times; i++ {
+			lockedFor, _ = store.RegisterFailure("email:a@a.ru", policy)
+		}
+		return lockedFor
+	}
+
+	if lockedFor := fail(2); lockedFor != 0 {
+		t.Fatalf("Locked too early for %v", lockedFor)
+	}
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute}
+	for i, want := range expected {
+		if i > 0 {
+			fail(2)
+		}
+		if lockedFor := fail(1); lockedFor != want {
+			t.Fatalf("Lockout %d: expected %v, got %v", i+1, want, lockedFor)
+		}
+		if remaining, _ := store.LockedFor("email:a@a.ru"); remaining != want {
+			t.Errorf("Lockout %d: expected remaining %v, got %v", i+1, want, remaining)
+		}
+		clock.now = clock.now.Add(want)
+		if remaining, _ := store.LockedFor("email:a@a.ru"); remaining != 0 {
+			t.Errorf("Lockout %d did not expire", i+1)
+		}
+	}
+
+	store.Reset("email:a@a.ru")
+	if lockedFor := fail(3); lockedFor != time.Minute {
+		t.Errorf("Reset must restart backoff, got %v", lockedFor)
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limit - ведро на Requests токенов, которое полностью наполняется за Per.
+// Нулевой Requests отключает ограничение
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// LockoutPolicy блокирует ключ после MaxFailures неудач подряд. Каждая следующая
+// блокировка вдвое длиннее предыдущей, но не больше MaxDuration.
+// Счётчики сбрасываются, если неудач не было дольше MaxDuration
+type LockoutPolicy struct {
+	MaxFailures  int
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+func (p LockoutPolicy) Disabled() bool {
+	return p.MaxFailures <= 0 || p.BaseDuration <= 0
+}
+
+func (p LockoutPolicy) duration(lockouts int) time.Duration {
+	d := p.BaseDuration
+	for i := 1; i < lockouts && d < p.MaxDuration; i++ {
+		d *= 2
+	}
+	if p.MaxDuration > 0 && d > p.MaxDuration {
+		d = p.MaxDuration
+	}
+	return d
+}
+
+// Store хранит состояние ведёр. Реализация в памяти подходит для одного экземпляра,
+// для нескольких нужна общая, например на Redis
+type Store interface {
+	Take(key string, limit Limit) (Result, error)
+}
+
+type LockoutStore interface {
+	LockedFor(key string) (time.Duration, error)
+	RegisterFailure(key string, policy LockoutPolicy) (time.Duration, error)
+	Reset(key string) error
+}
+
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
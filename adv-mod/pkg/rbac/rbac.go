@@ -0,0 +1,49 @@
+package rbac
+
+import "slices"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Разрешения заодно служат scopes API ключей
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionAuditRead   = "audit:read"
+	PermissionLinksRead   = "links:read"
+	PermissionLinksWrite  = "links:write"
+	PermissionStatRead    = "stat:read"
+	PermissionMetricsRead = "metrics:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser: {
+		PermissionLinksRead,
+		PermissionLinksWrite,
+		PermissionStatRead,
+	},
+	RoleAdmin: {
+		PermissionLinksRead,
+		PermissionLinksWrite,
+		PermissionStatRead,
+		PermissionUsersRead,
+		PermissionUsersWrite,
+		PermissionAuditRead,
+		PermissionMetricsRead,
+	},
+}
+
+func Roles() []string {
+	return []string{RoleUser, RoleAdmin}
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func HasPermission(role, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
@@ -2,15 +2,71 @@ package request
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"mime"
+	"net/http"
+	"strings"
 )
 
-func Decode[T any](body io.ReadCloser) (T, error) {
+var (
+	ErrTrailingData         = errors.New("request body must contain a single JSON value")
+	ErrUnsupportedMediaType = errors.New("unsupported content type")
+	ErrMalformedForm        = errors.New("malformed form body")
+)
+
+func Decode[T any](body io.ReadCloser, options ...Option) (T, error) {
+	return decodeJson[T](body, newOptions(options))
+}
+
+func decodeJson[T any](body io.ReadCloser, opts DecodeOptions) (T, error) {
+	defer body.Close()
 
 	var payload T
-	err := json.NewDecoder(body).Decode(&payload)
+	var reader io.Reader = body
+	if opts.MaxBodySize > 0 {
+		reader = http.MaxBytesReader(nil, body, opts.MaxBodySize)
+	}
+	decoder := json.NewDecoder(reader)
+	if opts.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(&payload)
 	if err != nil {
 		return payload, err
 	}
+	if opts.DisallowTrailingData {
+		err = decoder.Decode(&struct{}{})
+		if !errors.Is(err, io.EOF) {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return payload, err
+			}
+			return payload, ErrTrailingData
+		}
+	}
 	return payload, nil
 }
+
+// decodeRequest выбирает способ разбора по Content-Type запроса
+func decodeRequest[T any](r *http.Request, opts DecodeOptions) (T, error) {
+	var payload T
+	mediaType := ""
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return payload, ErrUnsupportedMediaType
+		}
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return decodeJson[T](r.Body, opts)
+	case mediaType == "" && !opts.RequireJson:
+		return decodeJson[T](r.Body, opts)
+	case (mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data") && !opts.RequireJson:
+		return decodeForm[T](r, opts)
+	}
+	return payload, ErrUnsupportedMediaType
+}
@@ -0,0 +1,195 @@
+package request_test
+
+import (
+	"adv-mod/pkg/request"
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestDecodeOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		options []request.Option
+		wantErr bool
+	}{
+		{name: "Valid", body: `{"email":"a@a.ru"}`},
+		{name: "Unknown field", body: `{"email":"a@a.ru","x":1}`, wantErr: true},
+		{name: "Unknown field allowed", body: `{"email":"a@a.ru","x":1}`, options: []request.Option{request.AllowUnknownFields()}},
+		{name: "Trailing data", body: `{"email":"a@a.ru"} {"email":"b@b.ru"}`, wantErr: true},
+		{name: "Trailing garbage", body: `{"email":"a@a.ru"}abc`, wantErr: true},
+		{name: "Trailing allowed", body: `{"email":"a@a.ru"} {}`, options: []request.Option{request.AllowTrailingData()}},
+		{name: "Too large", body: `{"email":"a@a.ru"}`, options: []request.Option{request.MaxBodySize(5)}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := &closeTracker{Reader: strings.NewReader(tc.body)}
+			_, err := request.Decode[testPayload](body, tc.options...)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if !body.closed {
+				t.Error("Body is not closed")
+			}
+		})
+	}
+}
+
+func TestDecodeTrailingDataError(t *testing.T) {
+	body := io.NopCloser(strings.NewReader(`{} []`))
+	_, err := request.Decode[testPayload](body)
+	if !errors.Is(err, request.ErrTrailingData) {
+		t.Errorf("Expected %v, got %v", request.ErrTrailingData, err)
+	}
+}
+
+func TestHandleBodyContentType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		options     []request.Option
+		status      int
+	}{
+		{name: "Json", contentType: "application/json; charset=utf-8", status: http.StatusOK},
+		{name: "Missing", contentType: "", status: http.StatusOK},
+		{name: "Missing strict", contentType: "", options: []request.Option{request.RequireJson()}, status: http.StatusUnsupportedMediaType},
+		{name: "Text", contentType: "text/plain", status: http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email":"a@a.ru","name":"Vasya"}`))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			var rw http.ResponseWriter = w
+			_, err := request.HandleBody[testPayload](&rw, req, tc.options...)
+			if err != nil && w.Code != tc.status {
+				t.Errorf("Expected %d, got %d: %v", tc.status, w.Code, err)
+			}
+			if err == nil && tc.status != http.StatusOK {
+				t.Errorf("Expected %d, got success", tc.status)
+			}
+		})
+	}
+}
+
+type formPayload struct {
+	Email  string                `form:"email" validate:"required,email"`
+	Age    int                   `json:"age"`
+	Tags   []string              `form:"tag"`
+	Agreed *bool                 `form:"agreed"`
+	Avatar *multipart.FileHeader `form:"avatar"`
+}
+
+func handleForm(contentType string, body io.Reader, options ...request.Option) (*formPayload, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	var rw http.ResponseWriter = w
+	payload, _ := request.HandleBody[formPayload](&rw, req, options...)
+	return payload, w
+}
+
+func TestHandleBodyUrlencoded(t *testing.T) {
+	form := url.Values{
+		"email":  {"a@a.ru"},
+		"age":    {"30"},
+		"tag":    {"go", "http"},
+		"agreed": {"true"},
+	}
+	payload, w := handleForm("application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if payload == nil {
+		t.Fatalf("Unexpected error response %d: %s", w.Code, w.Body.String())
+	}
+	if payload.Email != "a@a.ru" || payload.Age != 30 || len(payload.Tags) != 2 || payload.Agreed == nil || !*payload.Agreed {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestHandleBodyFormErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		form    url.Values
+		options []request.Option
+		status  int
+	}{
+		{name: "Wrong type", form: url.Values{"email": {"a@a.ru"}, "age": {"old"}}, status: http.StatusBadRequest},
+		{name: "Unknown field", form: url.Values{"email": {"a@a.ru"}, "role": {"admin"}}, status: http.StatusBadRequest},
+		{name: "Validation", form: url.Values{"email": {"nope"}}, status: http.StatusUnprocessableEntity},
+		{name: "Strict json", form: url.Values{"email": {"a@a.ru"}}, options: []request.Option{request.RequireJson()}, status: http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, w := handleForm("application/x-www-form-urlencoded", strings.NewReader(tc.form.Encode()), tc.options...)
+			if payload != nil || w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleBodyMalformedForm(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "Bad escape", contentType: "application/x-www-form-urlencoded", body: "email=%zz<script>"},
+		{name: "No boundary", contentType: "multipart/form-data", body: "email=a@a.ru"},
+		{name: "Broken multipart", contentType: "multipart/form-data; boundary=x", body: "--x\r\n<script>"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, w := handleForm(tc.contentType, strings.NewReader(tc.body))
+			if payload != nil || w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			resp := decodeError(t, w)
+			if resp.Code != request.ErrCodeMalformedForm {
+				t.Errorf("Expected %q, got %q", request.ErrCodeMalformedForm, resp.Code)
+			}
+			if resp.Message != request.ErrMalformedForm.Error() {
+				t.Errorf("Expected fixed message, got %q", resp.Message)
+			}
+		})
+	}
+}
+
+func TestHandleBodyMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("email", "a@a.ru")
+	writer.WriteField("age", "30")
+	file, _ := writer.CreateFormFile("avatar", "avatar.png")
+	file.Write([]byte("png"))
+	writer.Close()
+
+	payload, w := handleForm(writer.FormDataContentType(), &buf)
+	if payload == nil {
+		t.Fatalf("Unexpected error response %d: %s", w.Code, w.Body.String())
+	}
+	if payload.Email != "a@a.ru" || payload.Age != 30 {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+	if payload.Avatar == nil || payload.Avatar.Filename != "avatar.png" {
+		t.Errorf("Avatar is not decoded: %+v", payload.Avatar)
+	}
+}
@@ -0,0 +1,94 @@
+package request
+
+import (
+	"adv-mod/pkg/response"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	ErrCodeValidationFailed = "validation_failed"
+	ErrCodeMalformedJson    = "malformed_json"
+	ErrCodeUnknownField     = "unknown_field"
+	ErrCodeEmptyBody        = "empty_body"
+	ErrCodeBodyTooLarge     = "body_too_large"
+	ErrCodeMalformedForm    = "malformed_form"
+	ErrCodeUnsupportedMedia = "unsupported_media_type"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var maxBytesError *http.MaxBytesError
+	var unknownFieldError *UnknownFieldError
+	var formFieldError *FormFieldError
+
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		response.Error(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMedia, err.Error(), nil)
+	case errors.Is(err, io.EOF):
+		response.Error(w, http.StatusBadRequest, ErrCodeEmptyBody, "request body is empty", nil)
+	case errors.As(err, &maxBytesError):
+		response.Error(w, http.StatusRequestEntityTooLarge, ErrCodeBodyTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBytesError.Limit), nil)
+	case errors.Is(err, ErrMalformedForm):
+		// Текст ошибки разбора содержит куски тела, наружу уходит только код
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedForm, ErrMalformedForm.Error(), nil)
+	case errors.Is(err, ErrTrailingData):
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedJson, err.Error(), nil)
+	case errors.As(err, &unknownFieldError):
+		response.Error(w, http.StatusBadRequest, ErrCodeUnknownField, fmt.Sprintf("unknown field %q", unknownFieldError.Field), nil)
+	case errors.As(err, &formFieldError):
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedForm, formFieldError.Error(), nil)
+	case errors.As(err, &syntaxError):
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedJson, fmt.Sprintf("malformed JSON at position %d", syntaxError.Offset), nil)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedJson, "malformed JSON", nil)
+	case errors.As(err, &typeError):
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedJson, fmt.Sprintf("field %q must be of type %s", typeError.Field, typeError.Type), nil)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json не экспортирует тип этой ошибки, остаётся только текст
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		response.Error(w, http.StatusBadRequest, ErrCodeUnknownField, fmt.Sprintf("unknown field %s", field), nil)
+	default:
+		response.Error(w, http.StatusBadRequest, ErrCodeMalformedJson, "malformed request body", nil)
+	}
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		response.Error(w, http.StatusUnprocessableEntity, ErrCodeValidationFailed, err.Error(), nil)
+		return
+	}
+	translator := TranslatorFor(r)
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Message: translator.Translate(fe),
+		})
+	}
+	response.Error(w, http.StatusUnprocessableEntity, ErrCodeValidationFailed, "request validation failed", fields)
+}
+
+// fieldPath убирает имя корневой структуры: "LoginRequest.email" -> "email"
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
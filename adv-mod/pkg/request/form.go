@@ -0,0 +1,195 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const multipartMaxMemory = 32 << 20
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+type FormFieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FormFieldError) Error() string {
+	return fmt.Sprintf("field %q: %v", e.Field, e.Err)
+}
+
+func (e *FormFieldError) Unwrap() error {
+	return e.Err
+}
+
+// decodeForm заполняет T из application/x-www-form-urlencoded или multipart/form-data.
+// Имя поля берётся из тега form, затем из json, затем из имени поля структуры
+func decodeForm[T any](r *http.Request, opts DecodeOptions) (T, error) {
+	var payload T
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		maxMemory := int64(multipartMaxMemory)
+		if opts.MaxBodySize > 0 && opts.MaxBodySize < maxMemory {
+			maxMemory = opts.MaxBodySize
+		}
+		err = r.ParseMultipartForm(maxMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return payload, fmt.Errorf("%w: %w", ErrMalformedForm, err)
+	}
+
+	target := reflect.ValueOf(&payload).Elem()
+	if target.Kind() != reflect.Struct {
+		return payload, fmt.Errorf("form can not be decoded into %s", target.Type())
+	}
+	var files map[string][]*multipart.FileHeader
+	if r.MultipartForm != nil {
+		files = r.MultipartForm.File
+	}
+	known := map[string]bool{}
+	err = setFormFields(target, r.PostForm, files, known)
+	if err != nil {
+		return payload, err
+	}
+	if opts.DisallowUnknownFields {
+		for name := range r.PostForm {
+			if !known[name] {
+				return payload, &UnknownFieldError{Field: name}
+			}
+		}
+		for name := range files {
+			if !known[name] {
+				return payload, &UnknownFieldError{Field: name}
+			}
+		}
+	}
+	return payload, nil
+}
+
+func setFormFields(target reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader, known map[string]bool) error {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		value := target.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			err := setFormFields(value, values, files, known)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := formFieldName(field)
+		if name == "" {
+			continue
+		}
+		known[name] = true
+
+		switch {
+		case field.Type == fileHeaderType:
+			if fileHeaders := files[name]; len(fileHeaders) > 0 {
+				value.Set(reflect.ValueOf(fileHeaders[0]))
+			}
+			continue
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem() == fileHeaderType:
+			value.Set(reflect.ValueOf(files[name]))
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		err := setFormValue(value, raw)
+		if err != nil {
+			return &FormFieldError{Field: name, Err: err}
+		}
+	}
+	return nil
+}
+
+func formFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name != "" {
+		return name
+	}
+	return jsonTagName(field)
+}
+
+func setFormValue(value reflect.Value, raw []string) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		ptr := reflect.New(value.Type().Elem())
+		err := setFormValue(ptr.Elem(), raw)
+		if err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(raw), len(raw))
+		for i := range raw {
+			err := setScalar(slice.Index(i), raw[i])
+			if err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	return setScalar(value, raw[0])
+}
+
+func setScalar(value reflect.Value, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
@@ -1,20 +1,23 @@
 package request
 
 import (
-	"adv-mod/pkg/response"
 	"net/http"
 )
 
-func HandleBody[T any](w *http.ResponseWriter, r *http.Request) (*T, error) {
-	body, err := Decode[T](r.Body)
+func HandleBody[T any](w *http.ResponseWriter, r *http.Request, options ...Option) (*T, error) {
+	opts := newOptions(options)
+	if opts.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(*w, r.Body, opts.MaxBodySize)
+	}
+	body, err := decodeRequest[T](r, opts)
 	if err != nil {
-		response.Json(*w, err.Error(), 402)
+		writeDecodeError(*w, err)
 		return nil, err
 	}
 
 	err = IsValid(body)
 	if err != nil {
-		response.Json(*w, err.Error(), 402)
+		writeValidationError(*w, r, err)
 		return nil, err
 	}
 	return &body, nil
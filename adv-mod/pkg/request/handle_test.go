@@ -0,0 +1,112 @@
+package request_test
+
+import (
+	"adv-mod/pkg/request"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testPayload struct {
+	Email string `json:"email" validate:"required,email"`
+	Name  string `json:"name" validate:"required,min=2"`
+}
+
+func handle(body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	var rw http.ResponseWriter = w
+	request.HandleBody[testPayload](&rw, req)
+	return w
+}
+
+type errorBody struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message"`
+	Fields  []request.FieldError `json:"details"`
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var resp struct {
+		Error errorBody `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Body is not JSON: %v", err)
+	}
+	return resp.Error
+}
+
+func TestHandleBodyValidation(t *testing.T) {
+	w := handle(`{"email":"not-an-email","name":""}`, nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	resp := decodeError(t, w)
+	if resp.Code != request.ErrCodeValidationFailed {
+		t.Errorf("Expected %q, got %q", request.ErrCodeValidationFailed, resp.Code)
+	}
+	expected := map[string]string{
+		"email": "email",
+		"name":  "required",
+	}
+	if len(resp.Fields) != len(expected) {
+		t.Fatalf("Expected %d fields, got %+v", len(expected), resp.Fields)
+	}
+	for _, field := range resp.Fields {
+		if expected[field.Field] != field.Rule {
+			t.Errorf("Unexpected field error %+v", field)
+		}
+		if field.Message == "" {
+			t.Errorf("Empty message for %s", field.Field)
+		}
+	}
+}
+
+func TestHandleBodyRussianMessages(t *testing.T) {
+	w := handle(`{"email":"a@a.ru","name":""}`, map[string]string{
+		"Accept-Language": "ru-RU,ru;q=0.9,en;q=0.8",
+	})
+	resp := decodeError(t, w)
+	if len(resp.Fields) != 1 || resp.Fields[0].Message != "обязательное поле" {
+		t.Errorf("Expected russian message, got %+v", resp.Fields)
+	}
+}
+
+func TestHandleBodyDecodeErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "Empty body", body: "", status: http.StatusBadRequest, code: request.ErrCodeEmptyBody},
+		{name: "Malformed", body: `{"email":`, status: http.StatusBadRequest, code: request.ErrCodeMalformedJson},
+		{name: "Syntax", body: `{"email" "a"}`, status: http.StatusBadRequest, code: request.ErrCodeMalformedJson},
+		{name: "Wrong type", body: `{"email":1}`, status: http.StatusBadRequest, code: request.ErrCodeMalformedJson},
+		{name: "Unknown field", body: `{"email":"a@a.ru","name":"Vasya","admin":true}`, status: http.StatusBadRequest, code: request.ErrCodeUnknownField},
+		{
+			name:   "Too large",
+			body:   `{"name":"` + strings.Repeat("a", request.DefaultMaxBodySize) + `"}`,
+			status: http.StatusRequestEntityTooLarge,
+			code:   request.ErrCodeBodyTooLarge,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := handle(tc.body, nil)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+			resp := decodeError(t, w)
+			if resp.Code != tc.code {
+				t.Errorf("Expected %q, got %q", tc.code, resp.Code)
+			}
+		})
+	}
+}
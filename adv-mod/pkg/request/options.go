@@ -0,0 +1,51 @@
+package request
+
+const DefaultMaxBodySize = 1 << 20
+
+// DecodeOptions управляет тем, насколько строго Decode и HandleBody разбирают тело запроса
+type DecodeOptions struct {
+	MaxBodySize           int64
+	DisallowUnknownFields bool
+	DisallowTrailingData  bool
+	RequireJson           bool
+}
+
+type Option func(*DecodeOptions)
+
+func newOptions(options []Option) DecodeOptions {
+	opts := DecodeOptions{
+		MaxBodySize:           DefaultMaxBodySize,
+		DisallowUnknownFields: true,
+		DisallowTrailingData:  true,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// MaxBodySize ограничивает размер тела в байтах, 0 снимает ограничение
+func MaxBodySize(size int64) Option {
+	return func(opts *DecodeOptions) {
+		opts.MaxBodySize = size
+	}
+}
+
+func AllowUnknownFields() Option {
+	return func(opts *DecodeOptions) {
+		opts.DisallowUnknownFields = false
+	}
+}
+
+func AllowTrailingData() Option {
+	return func(opts *DecodeOptions) {
+		opts.DisallowTrailingData = false
+	}
+}
+
+// RequireJson отклоняет запросы без Content-Type: application/json, включая формы
+func RequireJson() Option {
+	return func(opts *DecodeOptions) {
+		opts.RequireJson = true
+	}
+}
@@ -0,0 +1,115 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Translator превращает ошибку валидатора в понятное человеку сообщение
+type Translator interface {
+	Translate(fe validator.FieldError) string
+}
+
+type TranslatorFunc func(fe validator.FieldError) string
+
+func (f TranslatorFunc) Translate(fe validator.FieldError) string {
+	return f(fe)
+}
+
+var (
+	translatorsMu sync.RWMutex
+	translators   = map[string]Translator{
+		"en": TranslatorFunc(translateEnglish),
+		"ru": TranslatorFunc(translateRussian),
+	}
+	DefaultLanguage = "en"
+)
+
+// RegisterTranslator добавляет или заменяет переводчик для языка из Accept-Language
+func RegisterTranslator(lang string, translator Translator) {
+	translatorsMu.Lock()
+	defer translatorsMu.Unlock()
+	translators[strings.ToLower(lang)] = translator
+}
+
+// TranslatorFor выбирает переводчик по заголовку Accept-Language запроса
+func TranslatorFor(r *http.Request) Translator {
+	translatorsMu.RLock()
+	defer translatorsMu.RUnlock()
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if translator, ok := translators[lang]; ok {
+			return translator
+		}
+	}
+	return translators[DefaultLanguage]
+}
+
+func translateEnglish(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters long", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters long", fe.Param())
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "eqfield":
+		return fmt.Sprintf("must match %s", fe.Param())
+	case "password":
+		return fmt.Sprintf("must be at least %d characters long and contain upper and lower case letters and a digit", PasswordMinLength)
+	case "phone":
+		return "must be a valid phone number"
+	case "slug":
+		return "may contain only lower case letters, digits and dashes"
+	}
+	return fmt.Sprintf("failed on the %q rule", fe.Tag())
+}
+
+func translateRussian(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "обязательное поле"
+	case "email":
+		return "должно быть корректным email адресом"
+	case "url":
+		return "должно быть корректным URL"
+	case "min":
+		return fmt.Sprintf("должно содержать не меньше %s символов", fe.Param())
+	case "max":
+		return fmt.Sprintf("должно содержать не больше %s символов", fe.Param())
+	case "len":
+		return fmt.Sprintf("должно содержать ровно %s символов", fe.Param())
+	case "gte":
+		return fmt.Sprintf("должно быть не меньше %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("должно быть не больше %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("должно быть одним из: %s", fe.Param())
+	case "eqfield":
+		return fmt.Sprintf("должно совпадать с %s", fe.Param())
+	case "password":
+		return fmt.Sprintf("должен содержать не меньше %d символов, заглавные и строчные буквы и цифру", PasswordMinLength)
+	case "phone":
+		return "должно быть корректным номером телефона"
+	case "slug":
+		return "может содержать только строчные буквы, цифры и дефисы"
+	}
+	return fmt.Sprintf("не прошло проверку %q", fe.Tag())
+}
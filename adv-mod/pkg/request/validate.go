@@ -1,12 +1,93 @@
 package request
 
 import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
 	"github.com/go-playground/validator/v10"
 )
 
+// validate создаётся один раз: validator кэширует разбор структур между вызовами
+var validate = newValidator()
+
+// Шаблоны правил phone и slug, их же публикует OpenAPI схема
+const (
+	PhonePattern = `^\+?[1-9][0-9]{7,14}$`
+	SlugPattern  = `^[a-z0-9]+(?:-[a-z0-9]+)*$`
+)
+
+var (
+	phoneRegexp = regexp.MustCompile(PhonePattern)
+	slugRegexp  = regexp.MustCompile(SlugPattern)
+)
+
+const PasswordMinLength = 8
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(jsonTagName)
+	v.RegisterValidation("password", isStrongPassword)
+	v.RegisterValidation("phone", isPhone)
+	v.RegisterValidation("slug", isSlug)
+	return v
+}
+
 func IsValid[T any](payload T) error {
-	validate := validator.New()
 	err := validate.Struct(payload)
 
 	return err
 }
+
+// RegisterValidation добавляет собственное правило. Вызывать только при старте,
+// до обработки запросов: validator не разрешает регистрацию параллельно с проверкой
+func RegisterValidation(tag string, fn validator.Func) error {
+	return validate.RegisterValidation(tag, fn)
+}
+
+// RegisterStructValidation добавляет проверку, которой нужен доступ к нескольким полям структуры
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...any) {
+	validate.RegisterStructValidation(fn, types...)
+}
+
+// jsonTagName подставляет в ошибки валидатора имя поля из json тега
+func jsonTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// isStrongPassword требует не меньше 8 символов, строчную и заглавную буквы и цифру
+func isStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len([]rune(password)) < PasswordMinLength {
+		return false
+	}
+	var hasUpper, hasLower, hasDigit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		}
+	}
+	return hasUpper && hasLower && hasDigit
+}
+
+func isPhone(fl validator.FieldLevel) bool {
+	phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(fl.Field().String())
+	return phoneRegexp.MatchString(phone)
+}
+
+func isSlug(fl validator.FieldLevel) bool {
+	return slugRegexp.MatchString(fl.Field().String())
+}
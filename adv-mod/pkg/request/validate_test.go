@@ -0,0 +1,114 @@
+package request_test
+
+import (
+	"adv-mod/pkg/request"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type signUpPayload struct {
+	Password        string `json:"password" validate:"required,password"`
+	PasswordConfirm string `json:"password_confirm" validate:"required,eqfield=Password"`
+	Phone           string `json:"phone" validate:"omitempty,phone"`
+	Slug            string `json:"slug" validate:"omitempty,slug"`
+}
+
+func failedRules(err error) map[string]string {
+	rules := map[string]string{}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			rules[fe.Field()] = fe.Tag()
+		}
+	}
+	return rules
+}
+
+func TestIsValidCustomRules(t *testing.T) {
+	testCases := []struct {
+		name    string
+		payload signUpPayload
+		failed  map[string]string
+	}{
+		{
+			name:    "Valid",
+			payload: signUpPayload{Password: "Secret123", PasswordConfirm: "Secret123", Phone: "+7 (999) 123-45-67", Slug: "my-link-1"},
+			failed:  map[string]string{},
+		},
+		{
+			name:    "Weak password",
+			payload: signUpPayload{Password: "secret", PasswordConfirm: "secret"},
+			failed:  map[string]string{"password": "password"},
+		},
+		{
+			name:    "Confirmation mismatch",
+			payload: signUpPayload{Password: "Secret123", PasswordConfirm: "Secret124"},
+			failed:  map[string]string{"password_confirm": "eqfield"},
+		},
+		{
+			name:    "Bad phone and slug",
+			payload: signUpPayload{Password: "Secret123", PasswordConfirm: "Secret123", Phone: "12", Slug: "My Link"},
+			failed:  map[string]string{"phone": "phone", "slug": "slug"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := failedRules(request.IsValid(tc.payload))
+			if len(got) != len(tc.failed) {
+				t.Fatalf("Expected %v, got %v", tc.failed, got)
+			}
+			for field, rule := range tc.failed {
+				if got[field] != rule {
+					t.Errorf("Expected %s to fail %q, got %v", field, rule, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	err := request.RegisterValidation("even_len", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String())%2 == 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type payload struct {
+		Code string `json:"code" validate:"even_len"`
+	}
+	if err := request.IsValid(payload{Code: "ab"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got := failedRules(request.IsValid(payload{Code: "abc"})); got["code"] != "even_len" {
+		t.Errorf("Expected even_len failure, got %v", got)
+	}
+}
+
+var benchPayload = signUpPayload{
+	Password:        "Secret123",
+	PasswordConfirm: "Secret123",
+	Phone:           "+79991234567",
+	Slug:            "my-link",
+}
+
+func BenchmarkIsValid(b *testing.B) {
+	for b.Loop() {
+		request.IsValid(benchPayload)
+	}
+}
+
+// BenchmarkIsValidNewValidator повторяет прежнее поведение IsValid с validator.New() на каждый вызов
+func BenchmarkIsValidNewValidator(b *testing.B) {
+	type payload struct {
+		Password        string `json:"password" validate:"required,min=8"`
+		PasswordConfirm string `json:"password_confirm" validate:"required,eqfield=Password"`
+		Phone           string `json:"phone" validate:"omitempty,e164"`
+		Slug            string `json:"slug" validate:"omitempty,alphanum"`
+	}
+	p := payload(benchPayload)
+	for b.Loop() {
+		validator.New().Struct(p)
+	}
+}
@@ -0,0 +1,114 @@
+package response
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeConflict            = "conflict"
+	CodeUnprocessableEntity = "unprocessable_entity"
+	CodeTooManyRequests     = "too_many_requests"
+	CodeInternal            = "internal_error"
+)
+
+// Заголовок выставляет middleware.RequestID, поэтому id берём прямо из ответа
+const requestIdHeader = "X-Request-ID"
+
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
+}
+
+// Problem - тело ошибки по RFC 7807, code и request_id передаются как расширения
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	Details   any    `json:"details,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
+}
+
+// Error пишет ошибку в едином формате {error:{code,message,details,request_id}},
+// либо application/problem+json, если клиент запросил его через Accept (см. Negotiate)
+func Error(w http.ResponseWriter, statusCode int, code, message string, details any) {
+	requestId := w.Header().Get(requestIdHeader)
+	if !wantsProblem(w) {
+		Json(w, ErrorBody{
+			Error: ErrorDetail{
+				Code:      code,
+				Message:   message,
+				Details:   details,
+				RequestId: requestId,
+			},
+		}, statusCode)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    message,
+		Code:      code,
+		Details:   details,
+		RequestId: requestId,
+	})
+}
+
+func BadRequest(w http.ResponseWriter, message string) {
+	Error(w, http.StatusBadRequest, CodeBadRequest, message, nil)
+}
+
+func Unauthorized(w http.ResponseWriter, message string) {
+	Error(w, http.StatusUnauthorized, CodeUnauthorized, message, nil)
+}
+
+func Forbidden(w http.ResponseWriter, message string) {
+	Error(w, http.StatusForbidden, CodeForbidden, message, nil)
+}
+
+func NotFound(w http.ResponseWriter, message string) {
+	Error(w, http.StatusNotFound, CodeNotFound, message, nil)
+}
+
+func Conflict(w http.ResponseWriter, message string) {
+	Error(w, http.StatusConflict, CodeConflict, message, nil)
+}
+
+func UnprocessableEntity(w http.ResponseWriter, message string, details any) {
+	Error(w, http.StatusUnprocessableEntity, CodeUnprocessableEntity, message, details)
+}
+
+// TooManyRequests выставляет Retry-After в целых секундах, округляя вверх
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	Error(w, http.StatusTooManyRequests, CodeTooManyRequests, message, nil)
+}
+
+// InternalServerError логирует причину, а клиенту отдаёт только общий текст
+func InternalServerError(w http.ResponseWriter, err error) {
+	slog.Error("internal error",
+		"error", err,
+		"request_id", w.Header().Get(requestIdHeader),
+	)
+	Error(w, http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError), nil)
+}
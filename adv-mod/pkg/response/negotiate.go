@@ -0,0 +1,52 @@
+package response
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const ProblemContentType = "application/problem+json"
+
+type problemWriter struct {
+	http.ResponseWriter
+}
+
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Negotiate переключает Error на application/problem+json, если его просит заголовок Accept
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if acceptsProblem(r.Header.Get("Accept")) {
+			w = &problemWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func acceptsProblem(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != ProblemContentType {
+			continue
+		}
+		return params["q"] != "0" && params["q"] != "0.0"
+	}
+	return false
+}
+
+// wantsProblem ищет problemWriter под обёртками других middleware
+func wantsProblem(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case *problemWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
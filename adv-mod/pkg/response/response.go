@@ -7,7 +7,7 @@ import (
 
 func Json(w http.ResponseWriter, data any, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(201)
+	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
 
 }
@@ -0,0 +1,107 @@
+package response_test
+
+import (
+	"adv-mod/pkg/response"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJsonStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	response.Json(w, map[string]string{"ok": "yes"}, http.StatusAccepted)
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected %d, got %d", http.StatusAccepted, w.Code)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "req-1")
+	response.Error(w, http.StatusConflict, response.CodeConflict, "user already exists", []string{"email"})
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected %d, got %d", http.StatusConflict, w.Code)
+	}
+	var body struct {
+		Error struct {
+			Code      string   `json:"code"`
+			Message   string   `json:"message"`
+			Details   []string `json:"details"`
+			RequestId string   `json:"request_id"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != response.CodeConflict || body.Error.Message != "user already exists" {
+		t.Errorf("Unexpected error %+v", body.Error)
+	}
+	if len(body.Error.Details) != 1 || body.Error.RequestId != "req-1" {
+		t.Errorf("Unexpected details/request id %+v", body.Error)
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	testCases := []struct {
+		name   string
+		write  func(w http.ResponseWriter)
+		status int
+	}{
+		{name: "400", write: func(w http.ResponseWriter) { response.BadRequest(w, "bad") }, status: http.StatusBadRequest},
+		{name: "401", write: func(w http.ResponseWriter) { response.Unauthorized(w, "who") }, status: http.StatusUnauthorized},
+		{name: "403", write: func(w http.ResponseWriter) { response.Forbidden(w, "no") }, status: http.StatusForbidden},
+		{name: "404", write: func(w http.ResponseWriter) { response.NotFound(w, "where") }, status: http.StatusNotFound},
+		{name: "409", write: func(w http.ResponseWriter) { response.Conflict(w, "twice") }, status: http.StatusConflict},
+		{name: "422", write: func(w http.ResponseWriter) { response.UnprocessableEntity(w, "bad", nil) }, status: http.StatusUnprocessableEntity},
+		{name: "500", write: func(w http.ResponseWriter) { response.InternalServerError(w, http.ErrAbortHandler) }, status: http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.write(w)
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestErrorProblemJson(t *testing.T) {
+	handler := response.Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.NotFound(w, "link not found")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json;q=0.5")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != response.ProblemContentType {
+		t.Fatalf("Expected %q, got %q", response.ProblemContentType, ct)
+	}
+	var problem response.Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Status != http.StatusNotFound || problem.Title != "Not Found" || problem.Detail != "link not found" {
+		t.Errorf("Unexpected problem %+v", problem)
+	}
+	if problem.Code != response.CodeNotFound {
+		t.Errorf("Expected code %q, got %q", response.CodeNotFound, problem.Code)
+	}
+}
+
+func TestErrorDefaultsToJson(t *testing.T) {
+	handler := response.Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.NotFound(w, "link not found")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+}